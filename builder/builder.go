@@ -0,0 +1,403 @@
+// Package builder builds queries in code, producing the same query.Query the parser would for the equivalent SQL, e.g.
+//
+//	q, err := builder.Select("a", "b").From("db.t").Where(builder.Eq("x", 1)).OrderBy("a", builder.Desc).Limit(10).Query()
+//
+// is what sqlparser.Parse returns for "SELECT a, b FROM db.t WHERE x = 1 ORDER BY a DESC LIMIT 10", but for the
+// positions of its nodes, which built queries don't have. Names are taken as written, with a dot qualifying a column
+// with its table or a table with its database.
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spasticus74/sqlparser/query"
+)
+
+// Direction is the direction of an ORDER BY field
+type Direction int
+
+const (
+	// Asc orders from lowest to highest
+	Asc Direction = iota
+	// Desc orders from highest to lowest
+	Desc
+)
+
+// Condition is a condition of a WHERE clause, e.g. Eq("x", 1)
+type Condition struct {
+	c   query.Condition
+	err error
+}
+
+// Eq is the condition "field = value". The value is a Column, e.g. Col("t.a"), a query.Literal or another expression,
+// or a Go value converted with query.LiteralOf.
+func Eq(field string, value interface{}) Condition { return condition(field, query.Eq, value) }
+
+// Ne is the condition "field != value", taking a value like Eq.
+func Ne(field string, value interface{}) Condition { return condition(field, query.Ne, value) }
+
+// Gt is the condition "field > value", taking a value like Eq.
+func Gt(field string, value interface{}) Condition { return condition(field, query.Gt, value) }
+
+// Lt is the condition "field < value", taking a value like Eq.
+func Lt(field string, value interface{}) Condition { return condition(field, query.Lt, value) }
+
+// Gte is the condition "field >= value", taking a value like Eq.
+func Gte(field string, value interface{}) Condition { return condition(field, query.Gte, value) }
+
+// Lte is the condition "field <= value", taking a value like Eq.
+func Lte(field string, value interface{}) Condition { return condition(field, query.Lte, value) }
+
+func condition(field string, operator query.Operator, value interface{}) Condition {
+	c := query.Condition{Operand1: field, Operand1IsField: true, Operator: operator}
+	e, err := expr(value)
+	switch e := e.(type) {
+	case query.Column:
+		c.Operand2, c.Operand2IsField = columnName(e), true
+	case query.Literal:
+		c.Operand2, c.Operand2Kind, c.Operand2Raw = e.Value, e.Kind, e.Raw
+	case query.BinaryExpr, query.UnaryExpr, query.FuncCall:
+		c.Operand2Expr = e
+	default:
+		if err == nil {
+			err = fmt.Errorf("at WHERE: unsupported value %T for %v", value, field)
+		}
+	}
+	return Condition{c: c, err: err}
+}
+
+// JoinCondition is a condition of a JOIN's ON clause, e.g. On("t.id", query.Eq, "u.t_id")
+type JoinCondition struct {
+	c   query.JoinCondition
+	err error
+}
+
+// On is the join condition "left operator right", where both sides are columns qualified with their table.
+func On(left string, operator query.Operator, right string) JoinCondition {
+	l, r := Col(left), Col(right)
+	if l.Table == "" || r.Table == "" {
+		return JoinCondition{err: fmt.Errorf("at ON: expected <tablename>.<fieldname>")}
+	}
+	return JoinCondition{c: query.JoinCondition{Table1: l.Table, Operand1: l.Name, Operator: operator, Table2: r.Table, Operand2: r.Name}}
+}
+
+// Col is a reference to a column, for a value that's a column rather than a literal.
+func Col(name string) query.Column {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return query.Column{Table: name[:i], Name: name[i+1:]}
+	}
+	return query.Column{Name: name}
+}
+
+// SelectBuilder builds a SELECT query
+type SelectBuilder struct {
+	state
+}
+
+// Select starts a SELECT of fields.
+func Select(fields ...string) *SelectBuilder {
+	return &SelectBuilder{state{q: query.Query{Type: query.Select, Fields: fields}}}
+}
+
+// Alias renames a SELECTed field, as in "field AS alias".
+func (b *SelectBuilder) Alias(field, alias string) *SelectBuilder {
+	if b.q.Aliases == nil {
+		b.q.Aliases = make(map[string]string)
+	}
+	b.q.Aliases[field] = alias
+	return b
+}
+
+// From sets the table selected from.
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.q.Database, b.q.TableName = splitTable(table)
+	return b
+}
+
+// Join adds a JOIN of table on conditions.
+func (b *SelectBuilder) Join(table string, on ...JoinCondition) *SelectBuilder {
+	return b.join("JOIN", table, on)
+}
+
+// LeftJoin adds a LEFT JOIN of table on conditions.
+func (b *SelectBuilder) LeftJoin(table string, on ...JoinCondition) *SelectBuilder {
+	return b.join("LEFT JOIN", table, on)
+}
+
+// RightJoin adds a RIGHT JOIN of table on conditions.
+func (b *SelectBuilder) RightJoin(table string, on ...JoinCondition) *SelectBuilder {
+	return b.join("RIGHT JOIN", table, on)
+}
+
+// InnerJoin adds an INNER JOIN of table on conditions.
+func (b *SelectBuilder) InnerJoin(table string, on ...JoinCondition) *SelectBuilder {
+	return b.join("INNER JOIN", table, on)
+}
+
+func (b *SelectBuilder) join(joinType, table string, on []JoinCondition) *SelectBuilder {
+	j := query.Join{Type: joinType, Table: table}
+	for _, c := range on {
+		b.fail(c.err)
+		j.Conditions = append(j.Conditions, c.c)
+	}
+	b.q.Joins = append(b.q.Joins, j)
+	return b
+}
+
+// Where adds conditions to the WHERE clause, all ANDed.
+func (b *SelectBuilder) Where(conditions ...Condition) *SelectBuilder {
+	b.where(conditions)
+	return b
+}
+
+// OrderBy adds a field to the ORDER BY clause.
+func (b *SelectBuilder) OrderBy(field string, dir Direction) *SelectBuilder {
+	b.orderBy(field, dir)
+	return b
+}
+
+// Limit sets the most rows to select.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.q.MaxRows, b.q.Limit = n, true
+	return b
+}
+
+// Query returns the built query, or the first error building it.
+func (b *SelectBuilder) Query() (query.Query, error) {
+	if len(b.q.Fields) == 0 {
+		b.fail(fmt.Errorf("at SELECT: expected field to SELECT"))
+	}
+	return b.result()
+}
+
+// InsertBuilder builds an INSERT query
+type InsertBuilder struct {
+	state
+}
+
+// Insert starts an INSERT INTO table.
+func Insert(table string) *InsertBuilder {
+	b := &InsertBuilder{state{q: query.Query{Type: query.Insert}}}
+	b.q.Database, b.q.TableName = splitTable(table)
+	return b
+}
+
+// Columns sets the fields inserted into, which are otherwise the table's columns in order.
+func (b *InsertBuilder) Columns(fields ...string) *InsertBuilder {
+	b.q.Fields = fields
+	return b
+}
+
+// Values adds a row to insert, of values taken like Update's Set.
+func (b *InsertBuilder) Values(values ...interface{}) *InsertBuilder {
+	row, err := exprs(values)
+	b.fail(err)
+	b.q.Inserts = append(b.q.Inserts, row)
+	return b
+}
+
+// Returning adds fields to the RETURNING clause.
+func (b *InsertBuilder) Returning(fields ...string) *InsertBuilder {
+	b.returning(fields)
+	return b
+}
+
+// Query returns the built query, or the first error building it.
+func (b *InsertBuilder) Query() (query.Query, error) {
+	if len(b.q.Inserts) == 0 {
+		b.fail(fmt.Errorf("at INSERT INTO: need at least one row to insert"))
+	}
+	for _, row := range b.q.Inserts {
+		if (b.q.Fields != nil && len(row) != len(b.q.Fields)) || len(row) != len(b.q.Inserts[0]) {
+			b.fail(fmt.Errorf("at INSERT INTO: value count doesn't match field count"))
+		}
+	}
+	return b.result()
+}
+
+// UpdateBuilder builds an UPDATE query
+type UpdateBuilder struct {
+	state
+}
+
+// Update starts an UPDATE of table.
+func Update(table string) *UpdateBuilder {
+	b := &UpdateBuilder{state{q: query.Query{Type: query.Update}}}
+	b.q.Database, b.q.TableName = splitTable(table)
+	return b
+}
+
+// Set adds the assignment "field = value" to the SET clause. The value is a query.Expr, e.g. Col("a") or a
+// query.BinaryExpr, or a Go value converted with query.LiteralOf.
+func (b *UpdateBuilder) Set(field string, value interface{}) *UpdateBuilder {
+	e, err := expr(value)
+	b.fail(err)
+	b.q.Updates = append(b.q.Updates, query.Assignment{Fields: []string{field}, Values: []query.Expr{e}})
+	return b
+}
+
+// Where adds conditions to the WHERE clause, all ANDed.
+func (b *UpdateBuilder) Where(conditions ...Condition) *UpdateBuilder {
+	b.where(conditions)
+	return b
+}
+
+// OrderBy adds a field to the ORDER BY clause.
+func (b *UpdateBuilder) OrderBy(field string, dir Direction) *UpdateBuilder {
+	b.orderBy(field, dir)
+	return b
+}
+
+// Limit sets the most rows to update.
+func (b *UpdateBuilder) Limit(n int) *UpdateBuilder {
+	b.q.MaxRows, b.q.Limit = n, true
+	return b
+}
+
+// Returning adds fields to the RETURNING clause.
+func (b *UpdateBuilder) Returning(fields ...string) *UpdateBuilder {
+	b.returning(fields)
+	return b
+}
+
+// Query returns the built query, or the first error building it.
+func (b *UpdateBuilder) Query() (query.Query, error) {
+	if len(b.q.Updates) == 0 {
+		b.fail(fmt.Errorf("at UPDATE: expected at least one field to update"))
+	}
+	if len(b.q.Conditions) == 0 {
+		b.fail(fmt.Errorf("at WHERE: WHERE clause is mandatory for UPDATE & DELETE"))
+	}
+	return b.result()
+}
+
+// DeleteBuilder builds a DELETE query
+type DeleteBuilder struct {
+	state
+}
+
+// Delete starts a DELETE FROM table.
+func Delete(table string) *DeleteBuilder {
+	b := &DeleteBuilder{state{q: query.Query{Type: query.Delete}}}
+	b.q.Database, b.q.TableName = splitTable(table)
+	return b
+}
+
+// Where adds conditions to the WHERE clause, all ANDed.
+func (b *DeleteBuilder) Where(conditions ...Condition) *DeleteBuilder {
+	b.where(conditions)
+	return b
+}
+
+// OrderBy adds a field to the ORDER BY clause.
+func (b *DeleteBuilder) OrderBy(field string, dir Direction) *DeleteBuilder {
+	b.orderBy(field, dir)
+	return b
+}
+
+// Limit sets the most rows to delete.
+func (b *DeleteBuilder) Limit(n int) *DeleteBuilder {
+	b.q.MaxRows, b.q.Limit = n, true
+	return b
+}
+
+// Returning adds fields to the RETURNING clause.
+func (b *DeleteBuilder) Returning(fields ...string) *DeleteBuilder {
+	b.returning(fields)
+	return b
+}
+
+// Query returns the built query, or the first error building it.
+func (b *DeleteBuilder) Query() (query.Query, error) {
+	if len(b.q.Conditions) == 0 {
+		b.fail(fmt.Errorf("at WHERE: WHERE clause is mandatory for UPDATE & DELETE"))
+	}
+	return b.result()
+}
+
+// state is the query being built, and the first error building it
+type state struct {
+	q   query.Query
+	err error
+}
+
+func (s *state) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *state) where(conditions []Condition) {
+	for _, c := range conditions {
+		s.fail(c.err)
+		s.q.Conditions = append(s.q.Conditions, c.c)
+	}
+}
+
+func (s *state) orderBy(field string, dir Direction) {
+	s.q.OrderFields = append(s.q.OrderFields, field)
+	if dir == Desc {
+		s.q.OrderDir = append(s.q.OrderDir, "DESC")
+	} else {
+		s.q.OrderDir = append(s.q.OrderDir, "ASC")
+	}
+}
+
+func (s *state) returning(fields []string) {
+	for _, f := range fields {
+		s.q.Returning = append(s.q.Returning, Col(f))
+	}
+}
+
+func (s *state) result() (query.Query, error) {
+	if s.err == nil && s.q.TableName == "" {
+		s.err = fmt.Errorf("table name cannot be empty")
+	}
+	if s.err != nil {
+		return query.Query{}, s.err
+	}
+	return s.q, nil
+}
+
+func exprs(values []interface{}) ([]query.Expr, error) {
+	es := make([]query.Expr, len(values))
+	var err error
+	for i, v := range values {
+		var e error
+		es[i], e = expr(v)
+		if err == nil {
+			err = e
+		}
+	}
+	return es, err
+}
+
+// expr converts a value to an expression, writing strings with quotes in them the way they're parsed.
+func expr(v interface{}) (query.Expr, error) {
+	if e, ok := v.(query.Expr); ok {
+		return e, nil
+	}
+	l, err := query.LiteralOf(v)
+	if err != nil {
+		return nil, err
+	}
+	if l.Kind == query.StringLiteral && strings.Contains(l.Value, "'") {
+		l.Raw = "'" + strings.Replace(l.Value, "'", "''", -1) + "'"
+	}
+	return l, nil
+}
+
+func columnName(c query.Column) string {
+	if c.Table == "" {
+		return c.Name
+	}
+	return c.Table + "." + c.Name
+}
+
+func splitTable(table string) (string, string) {
+	if i := strings.Index(table, "."); i >= 0 {
+		return table[:i], table[i+1:]
+	}
+	return "", table
+}
@@ -0,0 +1,122 @@
+package builder
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spasticus74/sqlparser"
+	"github.com/spasticus74/sqlparser/query"
+	"github.com/stretchr/testify/require"
+)
+
+type queryBuilder interface {
+	Query() (query.Query, error)
+}
+
+func TestBuilder(t *testing.T) {
+	ts := []struct {
+		Name    string
+		Builder queryBuilder
+		SQL     string
+		Err     error
+	}{
+		{
+			Name:    "SELECT works",
+			Builder: Select("a", "b").From("db.t").Where(Eq("x", 1)).OrderBy("a", Desc).Limit(10),
+			SQL:     "SELECT a, b FROM db.t WHERE x = 1 ORDER BY a DESC LIMIT 10",
+		},
+		{
+			Name: "SELECT with aliases, JOINs and several conditions works",
+			Builder: Select("t.a", "b").Alias("b", "c").From("t").
+				LeftJoin("u", On("t.id", query.Eq, "u.t_id"), On("t.v", query.Gte, "u.v")).
+				Where(Ne("a", "it's"), Gt("b", 1.5), Lte("c", Col("t.d")), Lt("d", nil), Gte("e", true)).
+				OrderBy("a", Asc).OrderBy("b", Desc),
+			SQL: "SELECT t.a, b AS c FROM t LEFT JOIN u ON t.id = u.t_id AND t.v >= u.v " +
+				"WHERE a != 'it''s' AND b > 1.5 AND c <= t.d AND d < NULL AND e >= TRUE ORDER BY a, b DESC",
+		},
+		{
+			Name:    "SELECT with a placeholder works",
+			Builder: Select("a").From("t").Where(Eq("a", query.Literal{Kind: query.PlaceholderLiteral, Value: "$1"})),
+			SQL:     "SELECT a FROM t WHERE a = $1",
+		},
+		{
+			Name:    "SELECT with an expression in a condition works",
+			Builder: Select("a").From("t").Where(Gt("a", query.BinaryExpr{Left: Col("b"), Operator: "*", Right: query.Literal{Kind: query.IntegerLiteral, Value: "2"}})),
+			SQL:     "SELECT a FROM t WHERE a > b * 2",
+		},
+		{
+			Name:    "INSERT works",
+			Builder: Insert("t").Columns("a", "b").Values(1, "x").Values(-2, nil).Returning("id"),
+			SQL:     "INSERT INTO t (a, b) VALUES (1, 'x'), (-2, NULL) RETURNING id",
+		},
+		{
+			Name:    "INSERT without fields works",
+			Builder: Insert("db.t").Values(1, 2),
+			SQL:     "INSERT INTO db.t VALUES (1, 2)",
+		},
+		{
+			Name: "UPDATE works",
+			Builder: Update("t").Set("n", query.BinaryExpr{Left: Col("n"), Operator: "+", Right: query.Literal{Kind: query.IntegerLiteral, Value: "1"}}).
+				Set("m", Col("u.m")).Where(Eq("id", 3)).Returning("n"),
+			SQL: "UPDATE t SET n = n + 1, m = u.m WHERE id = 3 RETURNING n",
+		},
+		{
+			Name:    "DELETE works",
+			Builder: Delete("t").Where(Eq("a", "x")).OrderBy("b", Asc).Limit(5),
+			SQL:     "DELETE FROM t WHERE a = 'x' ORDER BY b LIMIT 5",
+		},
+		{
+			Name:    "SELECT without fields fails",
+			Builder: Select().From("t"),
+			Err:     fmt.Errorf("at SELECT: expected field to SELECT"),
+		},
+		{
+			Name:    "SELECT without table fails",
+			Builder: Select("a"),
+			Err:     fmt.Errorf("table name cannot be empty"),
+		},
+		{
+			Name:    "JOIN on an unqualified field fails",
+			Builder: Select("a").From("t").Join("u", On("id", query.Eq, "u.t_id")),
+			Err:     fmt.Errorf("at ON: expected <tablename>.<fieldname>"),
+		},
+		{
+			Name:    "Condition with an unsupported value fails",
+			Builder: Select("a").From("t").Where(Eq("a", struct{}{})),
+			Err:     fmt.Errorf("value has unsupported type struct {}"),
+		},
+		{
+			Name:    "INSERT with uneven rows fails",
+			Builder: Insert("t").Columns("a", "b").Values(1, 2).Values(3),
+			Err:     fmt.Errorf("at INSERT INTO: value count doesn't match field count"),
+		},
+		{
+			Name:    "UPDATE without WHERE fails",
+			Builder: Update("t").Set("a", 1),
+			Err:     fmt.Errorf("at WHERE: WHERE clause is mandatory for UPDATE & DELETE"),
+		},
+		{
+			Name:    "UPDATE without SET fails",
+			Builder: Update("t").Where(Eq("a", 1)),
+			Err:     fmt.Errorf("at UPDATE: expected at least one field to update"),
+		},
+		{
+			Name:    "DELETE without WHERE fails",
+			Builder: Delete("t"),
+			Err:     fmt.Errorf("at WHERE: WHERE clause is mandatory for UPDATE & DELETE"),
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.Name, func(t *testing.T) {
+			q, err := tc.Builder.Query()
+			if tc.Err != nil {
+				require.EqualError(t, err, tc.Err.Error())
+				return
+			}
+			require.NoError(t, err)
+			parsed, err := sqlparser.Parse(tc.SQL)
+			require.NoError(t, err)
+			require.Equal(t, query.WithoutPositions(parsed), q)
+		})
+	}
+}
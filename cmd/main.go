@@ -16,5 +16,6 @@ func main() {
 	if err != nil {
 		log.Println(err)
 	}
-	fmt.Printf("%+#v", q)
+	fmt.Printf("%+#v\n", q)
+	fmt.Println(q.SQL())
 }
@@ -0,0 +1,170 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spasticus74/sqlparser/query"
+)
+
+// additiveOperators and multiplicativeOperators are the arithmetic operators parseExpr understands, by precedence.
+var additiveOperators = []string{"+", "-", "||"}
+
+var multiplicativeOperators = []string{"*", "/", "%"}
+
+// parseExpr parses a value expression starting at the current position, e.g. "count + 1" or "COALESCE(a, 'x')".
+func (p *parser) parseExpr() (query.Expr, error) {
+	return p.parseBinaryExpr(additiveOperators, p.parseTerm)
+}
+
+func (p *parser) parseTerm() (query.Expr, error) {
+	return p.parseBinaryExpr(multiplicativeOperators, p.parseUnary)
+}
+
+func (p *parser) parseBinaryExpr(operators []string, operand func() (query.Expr, error)) (query.Expr, error) {
+	start := p.position(p.i, p.i)
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := p.peek()
+		if !contains(operators, operator) {
+			return left, nil
+		}
+		p.pop()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = query.BinaryExpr{Left: left, Operator: operator, Right: right, Position: p.spanFrom(start)}
+	}
+}
+
+func (p *parser) parseUnary() (query.Expr, error) {
+	if p.peek() == "-" {
+		start := p.position(p.i, p.i)
+		p.pop()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		// a negative number is a literal of its own, e.g. "-5"
+		if l, ok := operand.(query.Literal); ok && isNumber(l.Kind) && !strings.HasPrefix(l.Value, "-") {
+			return query.Literal{Kind: l.Kind, Value: "-" + l.Value, Position: p.spanFrom(start)}, nil
+		}
+		return query.UnaryExpr{Operator: "-", Operand: operand, Position: p.spanFrom(start)}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (query.Expr, error) {
+	start := p.position(p.i, p.i)
+	if quotedValue, ln := p.peekQuotedStringWithLength(); ln > 0 {
+		raw := p.rawString(quotedValue, ln)
+		p.pop()
+		return query.Literal{Kind: query.StringLiteral, Value: quotedValue, Raw: raw, Position: p.spanFrom(start)}, nil
+	}
+	token, ln := p.peekWithLength()
+	if ln == 0 {
+		return nil, fmt.Errorf("expected quoted value")
+	}
+	if token == "(" {
+		p.pop()
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("expected closing parens")
+		}
+		p.pop()
+		// the parens are part of the expression, e.g. for underlining it
+		return withPosition(e, p.spanFrom(start)), nil
+	}
+	switch kind := literalKind(token); kind {
+	case query.IntegerLiteral, query.DecimalLiteral, query.FloatLiteral, query.PlaceholderLiteral:
+		p.pop()
+		return query.Literal{Kind: kind, Value: token, Position: p.spanFrom(start)}, nil
+	case query.NullLiteral, query.BoolLiteral:
+		p.pop()
+		return query.Literal{Kind: kind, Value: strings.ToUpper(token), Position: p.spanFrom(start)}, nil
+	}
+	if token == "*" {
+		p.pop()
+		return query.Column{Name: "*", Position: p.spanFrom(start)}, nil
+	}
+	if isDigit(token[0]) || (token[0] == '.' && len(token) > 1 && isDigit(token[1])) {
+		// e.g. "1.2.3" or "1st"
+		return nil, fmt.Errorf("malformed number %v", token)
+	}
+	// MySQL's VALUES(a), the value a row would have inserted into a, in ON DUPLICATE KEY UPDATE
+	values := strings.ToUpper(token) == "VALUES"
+	if !isIdentifier(token) && !values {
+		return nil, fmt.Errorf("expected quoted value")
+	}
+	p.pop()
+	if values && p.peek() != "(" {
+		return nil, fmt.Errorf("expected opening parens after VALUES")
+	}
+	if p.peek() != "(" {
+		c := p.column(token)
+		c.Position = p.spanFrom(start)
+		return c, nil
+	}
+	p.pop()
+	call := query.FuncCall{Name: p.identifier(token)}
+	if values {
+		call.Name = "VALUES"
+	}
+	for p.peek() != ")" {
+		if len(call.Args) > 0 {
+			if p.peek() != "," {
+				return nil, fmt.Errorf("expected comma or closing parens")
+			}
+			p.pop()
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+	}
+	p.pop()
+	call.Position = p.spanFrom(start)
+	return call, nil
+}
+
+// withPosition returns e at the given position.
+func withPosition(e query.Expr, position query.Position) query.Expr {
+	switch n := e.(type) {
+	case query.Column:
+		n.Position = position
+		return n
+	case query.AliasedExpr:
+		n.Position = position
+		return n
+	case query.Literal:
+		n.Position = position
+		return n
+	case query.BinaryExpr:
+		n.Position = position
+		return n
+	case query.UnaryExpr:
+		n.Position = position
+		return n
+	case query.FuncCall:
+		n.Position = position
+		return n
+	}
+	return e
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Package format lays out parsed queries as readable SQL.
+package format
+
+import (
+	"strings"
+
+	"github.com/spasticus74/sqlparser/query"
+)
+
+// Case is the letter case keywords are written in
+type Case int
+
+const (
+	// Upper writes keywords in upper case, e.g. SELECT
+	Upper Case = iota
+	// Lower writes keywords in lower case, e.g. select
+	Lower
+)
+
+// Options configures Format. The zero Options writes upper case keywords, indents by 2 spaces and doesn't wrap lines.
+type Options struct {
+	KeywordCase Case
+	// Indent is the number of spaces continuation lines are indented by, 2 if 0
+	Indent int
+	// OneColumnPerLine puts each SELECTed field and each UPDATE assignment on a line of its own
+	OneColumnPerLine bool
+	// AlignJoins pads the tables of consecutive JOINs so that their ON conditions line up
+	AlignJoins bool
+	// LineWidth is the width past which lists are wrapped onto more lines, or 0 not to wrap them
+	LineWidth int
+}
+
+// Format returns q as SQL laid out one clause per line, with its comments on lines of their own before it. SELECT,
+// INSERT, UPDATE and DELETE are laid out clause by clause; other statements are written on a single line.
+func Format(q query.Query, opts Options) string {
+	if opts.Indent == 0 {
+		opts.Indent = 2
+	}
+	f := formatter{opts: opts, indent: strings.Repeat(" ", opts.Indent)}
+	return strings.Join(f.lines(q), "\n")
+}
+
+type formatter struct {
+	opts   Options
+	indent string
+}
+
+// lines returns the lines of a query: its comments, its clauses, then for EXPLAIN, the lines of the query explained.
+func (f formatter) lines(q query.Query) []string {
+	lines := []string{}
+	for _, c := range q.Comments {
+		lines = append(lines, c.Text)
+	}
+	clauses := q.Clauses(f.opts.KeywordCase == Lower)
+	// JOINs are padded to the widest one
+	width := 0
+	for _, c := range clauses {
+		if isJoin(c) && len(c.Keyword) > width {
+			width = len(c.Keyword)
+		}
+	}
+	for _, c := range clauses {
+		switch {
+		case isJoin(c):
+			lines = append(lines, f.join(c, width)...)
+		case c.Conditions:
+			lines = append(lines, c.Keyword+" "+c.Items[0])
+			for _, item := range c.Items[1:] {
+				lines = append(lines, f.indent+item)
+			}
+		default:
+			lines = append(lines, f.list(c.Keyword, c.Items, f.onePerLine(c))...)
+		}
+	}
+	if q.Type == query.Explain && q.Explained != nil {
+		lines = append(lines, f.lines(*q.Explained)...)
+	}
+	return lines
+}
+
+func isJoin(c query.Clause) bool {
+	return strings.HasSuffix(c.Name, "JOIN")
+}
+
+// onePerLine reports whether the items of a clause go on lines of their own.
+func (f formatter) onePerLine(c query.Clause) bool {
+	switch c.Name {
+	case "SELECT":
+		return f.opts.OneColumnPerLine
+	case "SET", "ON DUPLICATE KEY UPDATE":
+		return f.opts.OneColumnPerLine && len(c.Items) > 1
+	case "VALUES":
+		return len(c.Items) > 1
+	}
+	return false
+}
+
+// join lays out a JOIN with its table padded to width if the JOINs are aligned, and a condition per line.
+func (f formatter) join(c query.Clause, width int) []string {
+	head := c.Keyword
+	if len(c.Items) == 0 {
+		return []string{head}
+	}
+	if f.opts.AlignJoins {
+		head += strings.Repeat(" ", width-len(head))
+	}
+	lines := []string{head + " " + c.Items[0]}
+	for _, item := range c.Items[1:] {
+		// "AND" ends where "ON" does
+		lines = append(lines, strings.Repeat(" ", len(head))+item)
+	}
+	return lines
+}
+
+// list lays out a clause made of a keyword and a comma separated list of items, either with an item per line, or
+// with as many items per line as fit in the line width.
+func (f formatter) list(keyword string, items []string, onePerLine bool) []string {
+	if onePerLine {
+		lines := []string{keyword}
+		for i, item := range items {
+			if i < len(items)-1 {
+				item += ","
+			}
+			lines = append(lines, f.indent+item)
+		}
+		return lines
+	}
+	lines := []string{}
+	line := keyword
+	for i, item := range items {
+		if i < len(items)-1 {
+			item += ","
+		}
+		if i > 0 && f.opts.LineWidth > 0 && len(line)+1+len(item) > f.opts.LineWidth {
+			lines = append(lines, line)
+			line = f.indent + item
+			continue
+		}
+		line += " " + item
+	}
+	return append(lines, line)
+}
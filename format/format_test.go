@@ -0,0 +1,135 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/spasticus74/sqlparser"
+	"github.com/spasticus74/sqlparser/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	ts := []struct {
+		Name     string
+		SQL      string
+		Options  Options
+		Expected string
+	}{
+		{
+			Name:     "SELECT works",
+			SQL:      "select a, b as c from 't' where a = '1' and b > 2 order by a desc",
+			Expected: "SELECT a, b AS c\nFROM t\nWHERE a = '1'\n  AND b > 2\nORDER BY a DESC",
+		},
+		{
+			Name:     "SELECT with one column per line and lower case keywords works",
+			SQL:      "SELECT a, b AS c, \"Select\" FROM 't' WHERE d = NULL",
+			Options:  Options{KeywordCase: Lower, Indent: 4, OneColumnPerLine: true},
+			Expected: "select\n    a,\n    b as c,\n    \"Select\"\nfrom t\nwhere d = NULL",
+		},
+		{
+			Name:     "SELECT with aligned JOINs works",
+			SQL:      "SELECT a FROM t LEFT JOIN users ON t.u = users.id AND t.v = users.v JOIN x ON t.x = x.id",
+			Options:  Options{AlignJoins: true},
+			Expected: "SELECT a\nFROM t\nLEFT JOIN users ON t.u = users.id\n               AND t.v = users.v\nJOIN x          ON t.x = x.id",
+		},
+		{
+			Name:     "SELECT with wrapped fields works",
+			SQL:      "SELECT alpha, beta, gamma, delta, epsilon FROM t",
+			Options:  Options{LineWidth: 20},
+			Expected: "SELECT alpha, beta,\n  gamma, delta,\n  epsilon\nFROM t",
+		},
+		{
+			Name:     "SELECT keeps TOP",
+			SQL:      "SELECT TOP 5 a FROM t",
+			Expected: "SELECT TOP 5 a\nFROM t",
+		},
+		{
+			Name:     "SELECT keeps LIMIT",
+			SQL:      "SELECT a FROM t ORDER BY a LIMIT 5",
+			Expected: "SELECT a\nFROM t\nORDER BY a\nLIMIT 5",
+		},
+		{
+			Name:     "INSERT with comments and hints works",
+			SQL:      "-- load\nINSERT INTO t /*+ SET_VAR(a=1) */ (a, b) VALUES (1, 'x'), (2, 'y') RETURNING id",
+			Expected: "-- load\nINSERT /*+ SET_VAR(a=1) */ INTO t (a, b)\nVALUES\n  (1, 'x'),\n  (2, 'y')\nRETURNING id",
+		},
+		{
+			Name:     "INSERT with ON DUPLICATE KEY UPDATE works",
+			SQL:      "INSERT INTO t (a, b) VALUES (1, 2) ON DUPLICATE KEY UPDATE a = VALUES(a), b = b + 1",
+			Options:  Options{KeywordCase: Lower, OneColumnPerLine: true},
+			Expected: "insert into t (a, b)\nvalues (1, 2)\non duplicate key update\n  a = values(a),\n  b = b + 1",
+		},
+		{
+			Name:     "UPDATE works",
+			SQL:      "UPDATE t SET a = 1, b = b + 1 WHERE c = 'x' RETURNING a",
+			Options:  Options{KeywordCase: Lower, OneColumnPerLine: true},
+			Expected: "update t\nset\n  a = 1,\n  b = b + 1\nwhere c = 'x'\nreturning a",
+		},
+		{
+			Name:     "UPDATE with OUTPUT and FROM works",
+			SQL:      "UPDATE t SET a = 1 OUTPUT inserted.a FROM t JOIN u ON t.id = u.id WHERE u.b = 2",
+			Expected: "UPDATE t\nSET a = 1\nOUTPUT inserted.a\nFROM t\nJOIN u ON t.id = u.id\nWHERE u.b = 2",
+		},
+		{
+			Name:     "DELETE works",
+			SQL:      "DELETE FROM t USING u WHERE t.id = u.id",
+			Expected: "DELETE FROM t\nUSING u\nWHERE t.id = u.id",
+		},
+		{
+			Name:     "EXPLAIN works",
+			SQL:      "EXPLAIN ANALYZE SELECT a FROM t WHERE b = 'Select'",
+			Options:  Options{KeywordCase: Lower},
+			Expected: "explain analyze\nselect a\nfrom t\nwhere b = 'Select'",
+		},
+		{
+			Name:     "other statements are a single line",
+			SQL:      "/* tz */ SET SESSION time_zone = '+00:00'",
+			Options:  Options{KeywordCase: Lower},
+			Expected: "/* tz */\nset session time_zone = '+00:00'",
+		},
+		{
+			Name:     "other statements have their keywords cased too",
+			SQL:      "GRANT SELECT ON TABLE t TO r WITH GRANT OPTION",
+			Options:  Options{KeywordCase: Lower},
+			Expected: "grant select on table t to r with grant option",
+		},
+		{
+			Name:     "SHOW has its keywords cased",
+			SQL:      "SHOW TABLES FROM db",
+			Options:  Options{KeywordCase: Lower},
+			Expected: "show tables from db",
+		},
+		{
+			Name:     "names and values that look like keywords keep their case",
+			SQL:      "SELECT \"FROM\" FROM t WHERE a = NULL ORDER BY \"FROM\" DESC LIMIT 3",
+			Options:  Options{KeywordCase: Lower},
+			Expected: "select \"FROM\"\nfrom t\nwhere a = NULL\norder by \"FROM\" desc\nlimit 3",
+		},
+		{
+			Name:     "user variables aren't quoted",
+			SQL:      "SET @x = 1",
+			Expected: "SET @x = 1",
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.Name, func(t *testing.T) {
+			q, err := sqlparser.Parse(tc.SQL)
+			require.NoError(t, err)
+			formatted := Format(q, tc.Options)
+			require.Equal(t, tc.Expected, formatted)
+
+			parsed, err := sqlparser.Parse(formatted)
+			require.NoError(t, err)
+			require.Equal(t, withoutComments(query.WithoutPositions(q)), withoutComments(query.WithoutPositions(parsed)))
+		})
+	}
+}
+
+func withoutComments(q query.Query) query.Query {
+	q.Comments, q.Hints = nil, nil
+	if q.Explained != nil {
+		explained := withoutComments(*q.Explained)
+		q.Explained = &explained
+	}
+	return q
+}
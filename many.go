@@ -0,0 +1,94 @@
+package sqlparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spasticus74/sqlparser/query"
+)
+
+// ErrNotParsed is the error of the queries ParseManyOpts skipped after an earlier query failed to parse.
+var ErrNotParsed = errors.New("not parsed: an earlier query failed")
+
+// ManyOptions configures ParseManyOpts.
+type ManyOptions struct {
+	// Workers is the number of goroutines parsing in parallel. It defaults to runtime.GOMAXPROCS(0).
+	Workers int
+	// ContinueOnError makes parsing carry on past queries that fail to parse, instead of stopping at the first one.
+	ContinueOnError bool
+	Dialect         Dialect
+}
+
+// ParseManyOpts is like ParseMany, parsing the queries in parallel. It returns a query and an error for each of
+// sqls, in the same order, and the first of those errors by index. Without ContinueOnError, the queries after the
+// first one to fail aren't parsed and have ErrNotParsed; if ctx is cancelled, the queries not parsed yet have its
+// error.
+func ParseManyOpts(ctx context.Context, sqls []string, opts ManyOptions) ([]query.Query, []error, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	qs := make([]query.Query, len(sqls))
+	errs := make([]error, len(sqls))
+
+	// queries are handed out in order, so every query before the first failure is parsed even without
+	// ContinueOnError, and the first error is the same as parsing sequentially
+	next := int64(-1)
+	failed := int64(len(sqls))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(sqls) {
+					return
+				}
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				if !opts.ContinueOnError && int64(i) > atomic.LoadInt64(&failed) {
+					errs[i] = ErrNotParsed
+					continue
+				}
+				qs[i], errs[i] = parse(sqls[i], opts.Dialect)
+				if errs[i] == nil {
+					continue
+				}
+				// don't hand back what was parsed before the error
+				qs[i] = query.Query{}
+				if !opts.ContinueOnError {
+					for f := atomic.LoadInt64(&failed); int64(i) < f; f = atomic.LoadInt64(&failed) {
+						if atomic.CompareAndSwapInt64(&failed, f, int64(i)) {
+							break
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i := int(failed) + 1; i < len(sqls); i++ {
+		// some may have been parsed before the failure was known
+		if err := ctx.Err(); err == nil || errs[i] != err {
+			qs[i], errs[i] = query.Query{}, ErrNotParsed
+		}
+	}
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if err == ctx.Err() {
+			return qs, errs, err
+		}
+		return qs, errs, fmt.Errorf("at index %v: %v", i, err)
+	}
+	return qs, errs, nil
+}
@@ -0,0 +1,188 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EquivalentOptions configures Equivalent
+type EquivalentOptions struct {
+	// IgnoreLiterals makes every literal match every other, e.g. "a = 1" and "a = 'x'"
+	IgnoreLiterals bool
+	// IgnoreConditionOrder ignores the order of the conditions of a WHERE clause or a JOIN, which are all ANDed
+	IgnoreConditionOrder bool
+	// IgnoreAliases ignores the aliases given with AS, e.g. "a AS x" and "a AS y" or just "a"
+	IgnoreAliases bool
+}
+
+// Equivalent reports whether two queries mean the same, given opts. Comments, the dialect, how string literals are
+// spelled, e.g. with escapes or without, and whether rows are limited with TOP or LIMIT never matter.
+func Equivalent(a, b Query, opts EquivalentOptions) bool {
+	return len(Diff(equivalenceForm(a, opts), equivalenceForm(b, opts))) == 0
+}
+
+func equivalenceForm(q Query, opts EquivalentOptions) Query {
+	return Rewrite(q, func(node Node) Node {
+		switch n := node.(type) {
+		case Literal:
+			if opts.IgnoreLiterals {
+				return Literal{}
+			}
+			n.Raw = ""
+			return n
+		case AliasedExpr:
+			if opts.IgnoreAliases {
+				return n.Expr
+			}
+		case Join:
+			if opts.IgnoreConditionOrder {
+				sortJoinConditions(n.Conditions)
+			}
+			return n
+		case Query:
+			n.Comments, n.Limit, n.Dialect = nil, false, ANSI
+			if opts.IgnoreAliases {
+				n.Aliases = nil
+			}
+			if opts.IgnoreConditionOrder {
+				n.sortConditions()
+			}
+			return n
+		}
+		return node
+	}).(Query)
+}
+
+// sortConditions puts the query's WHERE conditions in the order of their SQL.
+func (q Query) sortConditions() {
+	sort.SliceStable(q.Conditions, func(i, j int) bool {
+		return q.conditionSQL(q.Conditions[i]) < q.conditionSQL(q.Conditions[j])
+	})
+}
+
+// sortJoinConditions puts join conditions in the order of their SQL.
+func sortJoinConditions(cs []JoinCondition) {
+	sort.SliceStable(cs, func(i, j int) bool {
+		return Query{}.joinConditionSQL(cs[i]) < Query{}.joinConditionSQL(cs[j])
+	})
+}
+
+// Diff returns the differences from query a to query b, one per line, e.g. "condition removed: a = 1" or "table
+// changed from t to u". It's empty if the queries are the same but for the positions of their comments.
+func Diff(a, b Query) []string {
+	var d []string
+	wa, wb := writer{Query: a}, writer{Query: b}
+	changed := func(what string, from, to interface{}) {
+		if from != to {
+			d = append(d, fmt.Sprintf("%v changed from %v to %v", what, from, to))
+		}
+	}
+	changed("type", a.Type.name(), b.Type.name())
+	if a.TableName == "" && b.TableName == "" {
+		// e.g. USE or SHOW TABLES FROM, which name only a database
+		changed("database", databaseSQL(a), databaseSQL(b))
+	} else {
+		changed("table", a.tableSQL(), b.tableSQL())
+	}
+	d = append(d, diffList("field", wa.fieldList(), wb.fieldList())...)
+	d = append(d, diffList("join", wa.joinList(), wb.joinList())...)
+	d = append(d, diffList("assignment", wa.assignmentList(), wb.assignmentList())...)
+	d = append(d, diffList("row", wa.rowList(), wb.rowList())...)
+	d = append(d, diffList("FROM table", a.identifierList(a.From), b.identifierList(b.From))...)
+	d = append(d, diffList("USING table", a.identifierList(a.Using), b.identifierList(b.Using))...)
+	d = append(d, diffList("target table", a.identifierList(a.Targets), b.identifierList(b.Targets))...)
+	d = append(d, diffList("condition", a.conditionList(), b.conditionList())...)
+	d = append(d, diffList("ORDER BY field", wa.orderList(), wb.orderList())...)
+	changed("limit", a.MaxRows, b.MaxRows)
+	changed("LIMIT", a.Limit, b.Limit)
+	d = append(d, diffList("returned expression", wa.exprList(a.Returning), wb.exprList(b.Returning))...)
+	changed("OUTPUT", a.Output, b.Output)
+	changed("savepoint", a.Savepoint, b.Savepoint)
+	changed("scope", a.Scope, b.Scope)
+	changed("ANALYZE", a.Analyze, b.Analyze)
+	changed("SHOW", a.Show, b.Show)
+	d = append(d, diffList("privilege", wa.privilegeList(), wb.privilegeList())...)
+	d = append(d, diffList("object", wa.objectList(), wb.objectList())...)
+	d = append(d, diffList("grantee", a.Grantees, b.Grantees)...)
+	changed("GRANT OPTION", a.GrantOption, b.GrantOption)
+	d = append(d, diffList("comment", commentList(a.Comments), commentList(b.Comments))...)
+	d = append(d, diffList("hint", commentList(a.Hints), commentList(b.Hints))...)
+	changed("dialect", a.Dialect.name(), b.Dialect.name())
+	switch {
+	case a.Explained != nil && b.Explained != nil:
+		for _, e := range Diff(*a.Explained, *b.Explained) {
+			d = append(d, "explained query: "+e)
+		}
+	case a.Explained != nil || b.Explained != nil:
+		changed("explained query", explainedSQL(a), explainedSQL(b))
+	}
+	return d
+}
+
+// diffList returns the items removed from a and added to b, or that they were reordered.
+func diffList(what string, a, b []string) []string {
+	var d []string
+	count := map[string]int{}
+	for _, s := range b {
+		count[s]++
+	}
+	for _, s := range a {
+		if count[s] == 0 {
+			d = append(d, what+" removed: "+s)
+			continue
+		}
+		count[s]--
+	}
+	count = map[string]int{}
+	for _, s := range a {
+		count[s]++
+	}
+	for _, s := range b {
+		if count[s] == 0 {
+			d = append(d, what+" added: "+s)
+			continue
+		}
+		count[s]--
+	}
+	if len(d) == 0 && strings.Join(a, "\x00") != strings.Join(b, "\x00") {
+		d = append(d, what+"s reordered from "+strings.Join(a, ", ")+" to "+strings.Join(b, ", "))
+	}
+	return d
+}
+
+func (t Type) name() string {
+	if t < 0 || int(t) >= len(TypeString) {
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+	return TypeString[t]
+}
+
+func (d Dialect) name() string {
+	if d < 0 || int(d) >= len(DialectString) {
+		return fmt.Sprintf("Dialect(%d)", int(d))
+	}
+	return DialectString[d]
+}
+
+func commentList(cs []Comment) []string {
+	texts := make([]string, len(cs))
+	for i, c := range cs {
+		texts[i] = c.Text
+	}
+	return texts
+}
+
+func databaseSQL(q Query) string {
+	if q.Database == "" {
+		return "none"
+	}
+	return q.identifierSQL(q.Database)
+}
+
+func explainedSQL(q Query) string {
+	if q.Explained == nil {
+		return "none"
+	}
+	return q.Explained.SQL()
+}
@@ -0,0 +1,105 @@
+package query
+
+// Expr is a value expression, e.g. a column reference in a RETURNING list
+type Expr interface {
+	Node
+	expr()
+}
+
+// Column is a reference to a column, optionally qualified with its table name (e.g. "inserted.id")
+type Column struct {
+	// Table is the qualifying table name, empty if the column is unqualified
+	Table string
+	// Name is the column name, or "*" for all columns
+	Name string
+
+	Position Position
+}
+
+// AliasedExpr is an expression renamed with AS, e.g. "id AS user_id"
+type AliasedExpr struct {
+	Expr  Expr
+	Alias string
+
+	Position Position
+}
+
+func (Column) expr()      {}
+func (AliasedExpr) expr() {}
+
+// Literal is a constant value, e.g. 'hello', 42 or NULL
+type Literal struct {
+	Kind LiteralKind
+	// Value is the literal's value, without quotes for string literals
+	Value string
+	// Raw is the literal as written, e.g. 'it''s' or E'a\nb', when it isn't just Value in single quotes
+	Raw string
+
+	Position Position
+}
+
+// LiteralKind is the kind of value a Literal holds
+type LiteralKind int
+
+const (
+	// UnknownLiteral is the zero value for a LiteralKind
+	UnknownLiteral LiteralKind = iota
+	// StringLiteral represents a quoted string, e.g. 'hello'
+	StringLiteral
+	// IntegerLiteral represents an integer, e.g. 42, -7 or 0xFF
+	IntegerLiteral
+	// DecimalLiteral represents an exact decimal number, e.g. 19.99 or .5
+	DecimalLiteral
+	// FloatLiteral represents a number with an exponent, e.g. 1e10 or 2.5E-3
+	FloatLiteral
+	// NullLiteral represents NULL
+	NullLiteral
+	// BoolLiteral represents TRUE or FALSE
+	BoolLiteral
+	// PlaceholderLiteral represents a bind parameter, e.g. ?, $1 or :name
+	PlaceholderLiteral
+)
+
+// LiteralKindString is a string slice with the names of all literal kinds in order
+var LiteralKindString = []string{
+	"UnknownLiteral",
+	"StringLiteral",
+	"IntegerLiteral",
+	"DecimalLiteral",
+	"FloatLiteral",
+	"NullLiteral",
+	"BoolLiteral",
+	"PlaceholderLiteral",
+}
+
+// BinaryExpr is an arithmetic or string operation on two expressions, e.g. "count + 1"
+type BinaryExpr struct {
+	Left Expr
+	// Operator is one of "+", "-", "*", "/", "%" or "||"
+	Operator string
+	Right    Expr
+
+	Position Position
+}
+
+// UnaryExpr is an operation on a single expression, e.g. "-price"
+type UnaryExpr struct {
+	// Operator is "-"
+	Operator string
+	Operand  Expr
+
+	Position Position
+}
+
+// FuncCall is a function call, e.g. "COALESCE(a, 'x')"
+type FuncCall struct {
+	Name string
+	Args []Expr
+
+	Position Position
+}
+
+func (Literal) expr()    {}
+func (BinaryExpr) expr() {}
+func (UnaryExpr) expr()  {}
+func (FuncCall) expr()   {}
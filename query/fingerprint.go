@@ -0,0 +1,47 @@
+package query
+
+import "hash/fnv"
+
+// Fingerprint returns the shape of a query, as normalised SQL and its 64-bit FNV-1a hash, so that queries differing
+// only in their values group together. Every literal is replaced by a ? placeholder, the rows of a multi-row INSERT
+// are collapsed into one, the number of rows of a TOP or LIMIT is replaced by ? too, aliases and comments are
+// dropped, keywords are lower-cased and whitespace is canonical.
+// The conditions of a WHERE clause or a JOIN, which are all ANDed, are put in a fixed order, as are the sides of a
+// join condition comparing for (in)equality; everything else, e.g. the order of SELECTed fields or of UPDATE
+// assignments, is kept as it changes what the query does.
+func Fingerprint(q Query) (string, uint64) {
+	normalised := Rewrite(q, normalise).(Query)
+	sql := writer{Query: normalised, lower: true, maxRows: "?"}.sql()
+	h := fnv.New64a()
+	h.Write([]byte(sql))
+	return sql, h.Sum64()
+}
+
+func normalise(node Node) Node {
+	switch n := node.(type) {
+	case Literal:
+		return Literal{Kind: PlaceholderLiteral, Value: "?"}
+	case AliasedExpr:
+		return n.Expr
+	case Join:
+		for i, c := range n.Conditions {
+			if (c.Operator == Eq || c.Operator == Ne) && c.Table2+"."+c.Operand2 < c.Table1+"."+c.Operand1 {
+				n.Conditions[i] = JoinCondition{Table1: c.Table2, Operand1: c.Operand2, Operator: c.Operator, Table2: c.Table1, Operand2: c.Operand1}
+			}
+		}
+		sortJoinConditions(n.Conditions)
+		return n
+	case Query:
+		n.Aliases, n.Comments = nil, nil
+		if n.MaxRows > 0 {
+			// written as ?
+			n.MaxRows = 1
+		}
+		if len(n.Inserts) > 1 {
+			n.Inserts = n.Inserts[:1]
+		}
+		n.sortConditions()
+		return n
+	}
+	return node
+}
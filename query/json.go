@@ -0,0 +1,296 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONVersion is the version of the JSON encoding of queries, given as the "Version" of every encoded Query. It's
+// increased whenever the encoding changes in a way older decoders can't read.
+//
+// A Query is encoded as an object with its field names as keys, e.g.
+//
+//	{"Version": 1, "Type": "Select", "TableName": "t", "Fields": ["a"], "Returning": null, ...}
+//
+// Types, dialects, operators and literal kinds are encoded as their names in TypeString, DialectString, OperatorString
+// and LiteralKindString.
+// Expressions are objects tagged with a "Node" key naming their type, e.g.
+//
+//	{"Node": "BinaryExpr", "Left": {"Node": "Column", "Table": "", "Name": "n"}, "Operator": "+",
+//	 "Right": {"Node": "Literal", "Kind": "IntegerLiteral", "Value": "1", "Raw": ""}}
+const JSONVersion = 1
+
+// MarshalText encodes the type as its name in TypeString, e.g. "Select".
+func (t Type) MarshalText() ([]byte, error) {
+	if t < 0 || int(t) >= len(TypeString) {
+		return nil, fmt.Errorf("unknown query type %d", int(t))
+	}
+	return []byte(TypeString[t]), nil
+}
+
+// UnmarshalText decodes a type from its name in TypeString.
+func (t *Type) UnmarshalText(text []byte) error {
+	i, err := enumIndex(TypeString, "query type", text)
+	*t = Type(i)
+	return err
+}
+
+// MarshalText encodes the dialect as its name in DialectString, e.g. "MySQL".
+func (d Dialect) MarshalText() ([]byte, error) {
+	if d < 0 || int(d) >= len(DialectString) {
+		return nil, fmt.Errorf("unknown dialect %d", int(d))
+	}
+	return []byte(DialectString[d]), nil
+}
+
+// UnmarshalText decodes a dialect from its name in DialectString.
+func (d *Dialect) UnmarshalText(text []byte) error {
+	i, err := enumIndex(DialectString, "dialect", text)
+	*d = Dialect(i)
+	return err
+}
+
+// MarshalText encodes the operator as its name in OperatorString, e.g. "Eq".
+func (o Operator) MarshalText() ([]byte, error) {
+	if o < 0 || int(o) >= len(OperatorString) {
+		return nil, fmt.Errorf("unknown operator %d", int(o))
+	}
+	return []byte(OperatorString[o]), nil
+}
+
+// UnmarshalText decodes an operator from its name in OperatorString.
+func (o *Operator) UnmarshalText(text []byte) error {
+	i, err := enumIndex(OperatorString, "operator", text)
+	*o = Operator(i)
+	return err
+}
+
+// MarshalText encodes the literal kind as its name in LiteralKindString, e.g. "StringLiteral".
+func (k LiteralKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(LiteralKindString) {
+		return nil, fmt.Errorf("unknown literal kind %d", int(k))
+	}
+	return []byte(LiteralKindString[k]), nil
+}
+
+// UnmarshalText decodes a literal kind from its name in LiteralKindString.
+func (k *LiteralKind) UnmarshalText(text []byte) error {
+	i, err := enumIndex(LiteralKindString, "literal kind", text)
+	*k = LiteralKind(i)
+	return err
+}
+
+func enumIndex(names []string, what string, text []byte) (int, error) {
+	for i, name := range names {
+		if name == string(text) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %v %q", what, text)
+}
+
+// MarshalJSON encodes the query along with the JSONVersion of its encoding.
+func (q Query) MarshalJSON() ([]byte, error) {
+	type jsonQuery Query
+	return json.Marshal(struct {
+		Version int
+		jsonQuery
+	}{JSONVersion, jsonQuery(q)})
+}
+
+// UnmarshalJSON decodes a query encoded by MarshalJSON, failing if it's of a later JSONVersion.
+func (q *Query) UnmarshalJSON(data []byte) error {
+	type jsonQuery Query
+	aux := struct {
+		Version int
+		*jsonQuery
+		Inserts   [][]jsonExpr
+		Returning []jsonExpr
+	}{jsonQuery: (*jsonQuery)(q)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Version > JSONVersion {
+		return fmt.Errorf("unsupported query JSON version %v", aux.Version)
+	}
+	q.Inserts = nil
+	if aux.Inserts != nil {
+		q.Inserts = make([][]Expr, len(aux.Inserts))
+		for i, row := range aux.Inserts {
+			q.Inserts[i] = exprs(row)
+		}
+	}
+	q.Returning = exprs(aux.Returning)
+	return nil
+}
+
+// UnmarshalJSON decodes an assignment, whose values are tagged expressions.
+func (a *Assignment) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Fields         []string
+		Values         []jsonExpr
+		Position       Position
+		FieldPositions []Position
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	a.Fields, a.Values = aux.Fields, exprs(aux.Values)
+	a.Position, a.FieldPositions = aux.Position, aux.FieldPositions
+	return nil
+}
+
+// UnmarshalJSON decodes a condition, whose Operand2Expr is a tagged expression.
+func (c *Condition) UnmarshalJSON(data []byte) error {
+	type condition Condition
+	aux := struct {
+		*condition
+		Operand2Expr jsonExpr
+	}{condition: (*condition)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	c.Operand2Expr = aux.Operand2Expr.Expr
+	return nil
+}
+
+// MarshalJSON encodes the column as an expression tagged "Column".
+func (c Column) MarshalJSON() ([]byte, error) {
+	type column Column
+	return json.Marshal(struct {
+		Node string
+		column
+	}{"Column", column(c)})
+}
+
+// MarshalJSON encodes the aliased expression as an expression tagged "AliasedExpr".
+func (e AliasedExpr) MarshalJSON() ([]byte, error) {
+	type aliasedExpr AliasedExpr
+	return json.Marshal(struct {
+		Node string
+		aliasedExpr
+	}{"AliasedExpr", aliasedExpr(e)})
+}
+
+// MarshalJSON encodes the literal as an expression tagged "Literal".
+func (l Literal) MarshalJSON() ([]byte, error) {
+	type literal Literal
+	return json.Marshal(struct {
+		Node string
+		literal
+	}{"Literal", literal(l)})
+}
+
+// MarshalJSON encodes the binary expression as an expression tagged "BinaryExpr".
+func (e BinaryExpr) MarshalJSON() ([]byte, error) {
+	type binaryExpr BinaryExpr
+	return json.Marshal(struct {
+		Node string
+		binaryExpr
+	}{"BinaryExpr", binaryExpr(e)})
+}
+
+// MarshalJSON encodes the unary expression as an expression tagged "UnaryExpr".
+func (e UnaryExpr) MarshalJSON() ([]byte, error) {
+	type unaryExpr UnaryExpr
+	return json.Marshal(struct {
+		Node string
+		unaryExpr
+	}{"UnaryExpr", unaryExpr(e)})
+}
+
+// MarshalJSON encodes the function call as an expression tagged "FuncCall".
+func (f FuncCall) MarshalJSON() ([]byte, error) {
+	type funcCall FuncCall
+	return json.Marshal(struct {
+		Node string
+		funcCall
+	}{"FuncCall", funcCall(f)})
+}
+
+// UnmarshalExprJSON decodes an expression encoded by the MarshalJSON method of its type.
+func UnmarshalExprJSON(data []byte) (Expr, error) {
+	var e jsonExpr
+	err := json.Unmarshal(data, &e)
+	return e.Expr, err
+}
+
+// jsonExpr decodes a tagged expression into the Expr it holds.
+type jsonExpr struct {
+	Expr
+}
+
+func (e *jsonExpr) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		e.Expr = nil
+		return nil
+	}
+	var tag struct {
+		Node string
+	}
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return err
+	}
+	switch tag.Node {
+	case "Column":
+		var c Column
+		err := json.Unmarshal(data, &c)
+		e.Expr = c
+		return err
+	case "Literal":
+		var l Literal
+		err := json.Unmarshal(data, &l)
+		e.Expr = l
+		return err
+	case "AliasedExpr":
+		var aux struct {
+			Expr     jsonExpr
+			Alias    string
+			Position Position
+		}
+		err := json.Unmarshal(data, &aux)
+		e.Expr = AliasedExpr{Expr: aux.Expr.Expr, Alias: aux.Alias, Position: aux.Position}
+		return err
+	case "BinaryExpr":
+		var aux struct {
+			Left     jsonExpr
+			Operator string
+			Right    jsonExpr
+			Position Position
+		}
+		err := json.Unmarshal(data, &aux)
+		e.Expr = BinaryExpr{Left: aux.Left.Expr, Operator: aux.Operator, Right: aux.Right.Expr, Position: aux.Position}
+		return err
+	case "UnaryExpr":
+		var aux struct {
+			Operator string
+			Operand  jsonExpr
+			Position Position
+		}
+		err := json.Unmarshal(data, &aux)
+		e.Expr = UnaryExpr{Operator: aux.Operator, Operand: aux.Operand.Expr, Position: aux.Position}
+		return err
+	case "FuncCall":
+		var aux struct {
+			Name     string
+			Args     []jsonExpr
+			Position Position
+		}
+		err := json.Unmarshal(data, &aux)
+		e.Expr = FuncCall{Name: aux.Name, Args: exprs(aux.Args), Position: aux.Position}
+		return err
+	}
+	return fmt.Errorf("unknown expression node %q", tag.Node)
+}
+
+func exprs(es []jsonExpr) []Expr {
+	if es == nil {
+		return nil
+	}
+	unwrapped := make([]Expr, len(es))
+	for i, e := range es {
+		unwrapped[i] = e.Expr
+	}
+	return unwrapped
+}
@@ -0,0 +1,256 @@
+package query
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Param is a bind parameter placeholder found in a query
+type Param struct {
+	// Placeholder is the placeholder as written, e.g. "?", "$2" or ":tenant"
+	Placeholder string
+	// Index is the 1-based position of a positional parameter: n for "$n", or the ordinal of a "?" among all "?"s.
+	// It's 0 for named parameters.
+	Index int
+	// Name is the name of a ":name" parameter, without its prefix
+	Name string
+}
+
+// Params lists every bind parameter placeholder in the query, in the order they appear
+func (q Query) Params() []Param {
+	ps := []Param{}
+	q.bind(func(p Param, l Literal) (Literal, error) {
+		ps = append(ps, p)
+		return l, nil
+	})
+	return ps
+}
+
+// Bind returns a copy of the query with its positional placeholders ("?" and "$n") replaced by literals of the
+// given arguments. Arguments may be nil, bools, strings, byte slices, numbers, time.Time values or driver.Valuers.
+// It fails if the query has named placeholders, or if the argument count doesn't match the placeholders.
+func (q Query) Bind(args ...interface{}) (Query, error) {
+	used := make([]bool, len(args))
+	b, err := q.bind(func(p Param, _ Literal) (Literal, error) {
+		if p.Name != "" {
+			return Literal{}, fmt.Errorf("named parameter %v can't be bound by position", p.Placeholder)
+		}
+		if p.Index < 1 || p.Index > len(args) {
+			return Literal{}, fmt.Errorf("missing argument for parameter %v: got %v arguments", p.Placeholder, len(args))
+		}
+		used[p.Index-1] = true
+		return literalFromValue(p, args[p.Index-1])
+	})
+	if err != nil {
+		return q, err
+	}
+	for i, u := range used {
+		if !u {
+			return q, fmt.Errorf("argument %v doesn't match any parameter", i+1)
+		}
+	}
+	return b, nil
+}
+
+// BindNamed returns a copy of the query with its named placeholders (":name") replaced by literals of the values
+// with those names, which may be of the same types as for Bind. It fails if the query has positional placeholders,
+// or if the names don't match the placeholders.
+func (q Query) BindNamed(args map[string]interface{}) (Query, error) {
+	used := map[string]bool{}
+	b, err := q.bind(func(p Param, _ Literal) (Literal, error) {
+		if p.Name == "" {
+			return Literal{}, fmt.Errorf("positional parameter %v can't be bound by name", p.Placeholder)
+		}
+		v, ok := args[p.Name]
+		if !ok {
+			return Literal{}, fmt.Errorf("missing argument for parameter %v", p.Placeholder)
+		}
+		used[p.Name] = true
+		return literalFromValue(p, v)
+	})
+	if err != nil {
+		return q, err
+	}
+	for name := range args {
+		if !used[name] {
+			return q, fmt.Errorf("argument %v doesn't match any parameter", name)
+		}
+	}
+	return b, nil
+}
+
+// bind returns a copy of the query with every placeholder literal replaced by the result of f, called in the
+// order the placeholders appear. It stops at the first error.
+func (q Query) bind(f func(Param, Literal) (Literal, error)) (Query, error) {
+	if q.Explained != nil {
+		explained, err := q.Explained.bind(f)
+		q.Explained = &explained
+		return q, err
+	}
+
+	var err error
+	ordinal := 0
+	param := func(placeholder string) Param {
+		p := Param{Placeholder: placeholder}
+		switch placeholder[0] {
+		case '?':
+			ordinal++
+			p.Index = ordinal
+		case '$':
+			p.Index, _ = strconv.Atoi(placeholder[1:])
+		default:
+			p.Name = placeholder[1:]
+		}
+		return p
+	}
+	bindLiteral := func(l Literal) Literal {
+		if l.Kind != PlaceholderLiteral || err != nil {
+			return l
+		}
+		bound, ferr := f(param(l.Value), l)
+		if ferr != nil {
+			err = ferr
+			return l
+		}
+		// the bound value stands where the placeholder was
+		bound.Position = l.Position
+		return bound
+	}
+	var bindExpr func(e Expr) Expr
+	bindExprs := func(es []Expr) []Expr {
+		if es == nil {
+			return nil
+		}
+		bound := make([]Expr, len(es))
+		for i, e := range es {
+			bound[i] = bindExpr(e)
+		}
+		return bound
+	}
+	bindExpr = func(e Expr) Expr {
+		switch e := e.(type) {
+		case Literal:
+			return bindLiteral(e)
+		case BinaryExpr:
+			e.Left, e.Right = bindExpr(e.Left), bindExpr(e.Right)
+			return e
+		case UnaryExpr:
+			e.Operand = bindExpr(e.Operand)
+			return e
+		case FuncCall:
+			e.Args = bindExprs(e.Args)
+			return e
+		case AliasedExpr:
+			e.Expr = bindExpr(e.Expr)
+			return e
+		}
+		return e
+	}
+
+	if q.Updates != nil {
+		updates := make([]Assignment, len(q.Updates))
+		for i, u := range q.Updates {
+			u.Values = bindExprs(u.Values)
+			updates[i] = u
+		}
+		q.Updates = updates
+	}
+	if q.Inserts != nil {
+		inserts := make([][]Expr, len(q.Inserts))
+		for i, row := range q.Inserts {
+			inserts[i] = bindExprs(row)
+		}
+		q.Inserts = inserts
+	}
+	if q.Conditions != nil {
+		conditions := make([]Condition, len(q.Conditions))
+		for i, c := range q.Conditions {
+			if c.Operand2Expr != nil {
+				c.Operand2Expr = bindExpr(c.Operand2Expr)
+			} else if !c.Operand2IsField {
+				bound := bindLiteral(Literal{Kind: c.Operand2Kind, Value: c.Operand2, Position: c.Operand2Position})
+				c.Operand2, c.Operand2Kind = bound.Value, bound.Kind
+			}
+			conditions[i] = c
+		}
+		q.Conditions = conditions
+	}
+	q.Returning = bindExprs(q.Returning)
+	return q, err
+}
+
+// literalFromValue converts a bound argument into a literal for parameter p.
+func literalFromValue(p Param, v interface{}) (Literal, error) {
+	l, err := LiteralOf(v)
+	if e, ok := err.(valueError); ok {
+		return Literal{}, fmt.Errorf("argument for parameter %v %v", p.Placeholder, e.reason)
+	}
+	if err != nil {
+		return Literal{}, fmt.Errorf("argument for parameter %v: %v", p.Placeholder, err)
+	}
+	return l, nil
+}
+
+// valueError is the error for a value that can't be written as a literal, e.g. "has unsupported type struct {}".
+type valueError struct {
+	reason string
+}
+
+func (e valueError) Error() string {
+	return "value " + e.reason
+}
+
+// LiteralOf converts a Go value into the literal it's written as, the same way Bind converts its arguments: nil is
+// NULL, a bool is TRUE or FALSE, a string, []byte or time.Time is a string, and an integer or float is a number.
+// A driver.Valuer is converted through its value. Strings are written for the Dialect of the query they're in, with
+// their backslashes doubled in MySQL. A time.Time is written as RFC 3339 with nanoseconds, e.g.
+// '2006-01-02T15:04:05.999999999Z', which MySQL DATETIME columns don't accept as it is: give those a string like
+// t.Format("2006-01-02 15:04:05.999999") instead.
+func LiteralOf(v interface{}) (Literal, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return Literal{}, err
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		return Literal{Kind: NullLiteral, Value: "NULL"}, nil
+	case bool:
+		if v {
+			return Literal{Kind: BoolLiteral, Value: "TRUE"}, nil
+		}
+		return Literal{Kind: BoolLiteral, Value: "FALSE"}, nil
+	case string:
+		return Literal{Kind: StringLiteral, Value: v}, nil
+	case []byte:
+		return Literal{Kind: StringLiteral, Value: string(v)}, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return Literal{Kind: IntegerLiteral, Value: fmt.Sprintf("%d", v)}, nil
+	case float32:
+		return floatLiteral(float64(v), 32)
+	case float64:
+		return floatLiteral(v, 64)
+	case time.Time:
+		return Literal{Kind: StringLiteral, Value: v.Format(time.RFC3339Nano)}, nil
+	}
+	return Literal{}, valueError{fmt.Sprintf("has unsupported type %T", v)}
+}
+
+func floatLiteral(f float64, bitSize int) (Literal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Literal{}, valueError{"is not a finite number"}
+	}
+	value := strconv.FormatFloat(f, 'g', -1, bitSize)
+	switch {
+	case strings.ContainsRune(value, 'e'):
+		return Literal{Kind: FloatLiteral, Value: value}, nil
+	case strings.ContainsRune(value, '.'):
+		return Literal{Kind: DecimalLiteral, Value: value}, nil
+	}
+	return Literal{Kind: IntegerLiteral, Value: value}, nil
+}
@@ -1,19 +1,109 @@
 package query
 
-// Query represents a parsed query
+// Query represents a parsed query. Its names, e.g. of tables and fields, are as written but for their quoted parts,
+// which are double-quoted whatever quotes they were written with, as in t."Order Id" for t.[Order Id].
 type Query struct {
 	Type        Type
 	Database    string
 	TableName   string
 	Conditions  []Condition
-	Updates     map[string]string
-	Inserts     [][]string
-	Fields      []string // Used for SELECT (i.e. SELECTed field names) and INSERT (INSERTEDed field names)
+	Updates     []Assignment // Used for UPDATE (i.e. the SET clause, in order), SET (i.e. the variables being set) and INSERT (i.e. ON DUPLICATE KEY UPDATE)
+	Inserts     [][]Expr     // Used for INSERT (i.e. the rows of the VALUES clause)
+	Fields      []string     // Used for SELECT (i.e. SELECTed field names) and INSERT (INSERTEDed field names)
 	Aliases     map[string]string
 	OrderFields []string
 	OrderDir    []string
 	Joins       []Join
-	MaxRows     int
+	MaxRows     int         // Used for SELECT TOP and LIMIT, and UPDATE and DELETE ... LIMIT
+	Limit       bool        // Set when MaxRows was given as a LIMIT clause rather than SELECT TOP
+	Returning   []Expr      // Used for INSERT, UPDATE and DELETE (i.e. RETURNING or OUTPUT expressions)
+	Output      bool        // Set when Returning was given as a T-SQL OUTPUT clause rather than RETURNING
+	From        []string    // Used for UPDATE (i.e. the tables of a Postgres or T-SQL UPDATE ... FROM, whose JOINs are then Joins)
+	Using       []string    // Used for DELETE (i.e. the tables of a DELETE ... USING)
+	Targets     []string    // Used for multi-table DELETE (i.e. the tables named between DELETE and FROM)
+	Savepoint   string      // Used for SAVEPOINT, RELEASE and ROLLBACK TO (i.e. the savepoint name)
+	Scope       string      // Used for SET (i.e. SESSION, LOCAL or GLOBAL, if given)
+	Explained   *Query      // Used for EXPLAIN (i.e. the query being explained)
+	Analyze     bool        // Used for EXPLAIN (i.e. set for EXPLAIN ANALYZE)
+	Show        string      // Used for SHOW (i.e. what is shown, e.g. TABLES, COLUMNS or a setting name)
+	Privileges  []Privilege // Used for GRANT and REVOKE (i.e. the privileges granted or revoked)
+	Objects     []Object    // Used for GRANT and REVOKE (i.e. what the privileges apply ON)
+	Grantees    []string    // Used for GRANT and REVOKE (i.e. the roles or users TO or FROM which privileges are granted or revoked)
+	GrantOption bool        // Used for GRANT ... WITH GRANT OPTION and REVOKE GRANT OPTION FOR
+	Comments    []Comment   // The comments in the query, which are otherwise ignored
+	Hints       []Comment   // The MySQL optimizer hints in the query, e.g. "/*+ NO_ICP(t) */"
+	Dialect     Dialect     // The dialect the query is written in, which decides how its strings are written
+
+	// The positions of what's held as names rather than nodes, each in the same order as the names, or nil if the
+	// query wasn't parsed
+	TablePosition       Position   // Where TableName is, including its database
+	FieldPositions      []Position // Where each of Fields is
+	OrderFieldPositions []Position // Where each of OrderFields is
+	FromPositions       []Position // Where each of From is
+	UsingPositions      []Position // Where each of Using is
+	TargetPositions     []Position // Where each of Targets is
+}
+
+// Position is where a node is in the parsed SQL. It's the zero Position if the node wasn't parsed, e.g. if it was
+// built in code.
+type Position struct {
+	// Start and End are the byte offsets of the node in the parsed SQL
+	Start int
+	End   int
+	// Line and Column are where the node starts, counting from 1, with the column in bytes
+	Line   int
+	Column int
+}
+
+// WithoutPositions returns a copy of q without the positions of its nodes, as if it had been built in code, e.g. to
+// compare queries parsed from differently laid out SQL. The positions of its comments are kept.
+func WithoutPositions(q Query) Query {
+	return Rewrite(q, func(node Node) Node {
+		switch n := node.(type) {
+		case Query:
+			n.TablePosition = Position{}
+			n.FieldPositions, n.OrderFieldPositions = nil, nil
+			n.FromPositions, n.UsingPositions, n.TargetPositions = nil, nil, nil
+			for i := range n.Objects {
+				n.Objects[i].Position = Position{}
+			}
+			return n
+		case TableRef:
+			n.Position = Position{}
+			return n
+		case Join:
+			n.Position, n.TablePosition = Position{}, Position{}
+			return n
+		case JoinCondition:
+			n.Position, n.Operand1Position, n.Operand2Position = Position{}, Position{}, Position{}
+			return n
+		case Condition:
+			n.Position, n.Operand1Position, n.Operand2Position = Position{}, Position{}, Position{}
+			return n
+		case Assignment:
+			n.Position, n.FieldPositions = Position{}, nil
+			return n
+		case Column:
+			n.Position = Position{}
+			return n
+		case AliasedExpr:
+			n.Position = Position{}
+			return n
+		case Literal:
+			n.Position = Position{}
+			return n
+		case BinaryExpr:
+			n.Position = Position{}
+			return n
+		case UnaryExpr:
+			n.Position = Position{}
+			return n
+		case FuncCall:
+			n.Position = Position{}
+			return n
+		}
+		return node
+	}).(Query)
 }
 
 // Type is the type of SQL query, e.g. SELECT/UPDATE
@@ -30,6 +120,30 @@ const (
 	Insert
 	// Delete represents a DELETE query
 	Delete
+	// Begin represents a BEGIN or START TRANSACTION statement
+	Begin
+	// Commit represents a COMMIT statement
+	Commit
+	// Rollback represents a ROLLBACK statement, optionally to a savepoint
+	Rollback
+	// Savepoint represents a SAVEPOINT statement
+	Savepoint
+	// Release represents a RELEASE SAVEPOINT statement
+	Release
+	// Set represents a SET statement changing session settings
+	Set
+	// Use represents a USE statement changing the default database
+	Use
+	// Explain represents an EXPLAIN statement wrapping another query
+	Explain
+	// Show represents a SHOW statement, e.g. SHOW TABLES
+	Show
+	// Describe represents a DESCRIBE statement
+	Describe
+	// Grant represents a GRANT statement
+	Grant
+	// Revoke represents a REVOKE statement
+	Revoke
 )
 
 // TypeString is a string slice with the names of all types in order
@@ -39,6 +153,45 @@ var TypeString = []string{
 	"Update",
 	"Insert",
 	"Delete",
+	"Begin",
+	"Commit",
+	"Rollback",
+	"Savepoint",
+	"Release",
+	"Set",
+	"Use",
+	"Explain",
+	"Show",
+	"Describe",
+	"Grant",
+	"Revoke",
+}
+
+// HasTable reports whether queries of this type operate on a table, i.e. SELECT, UPDATE, INSERT and DELETE
+func (t Type) HasTable() bool {
+	return t == Select || t == Update || t == Insert || t == Delete
+}
+
+// IsTransactionControl reports whether the type begins or ends a transaction or manages a savepoint within it
+func (t Type) IsTransactionControl() bool {
+	return t == Begin || t == Commit || t == Rollback || t == Savepoint || t == Release
+}
+
+// Dialect selects the lexical rules of a SQL dialect, where they differ.
+type Dialect int
+
+const (
+	// ANSI is standard SQL, as spoken by Postgres and SQL Server: backslashes in strings are plain characters, except
+	// in E'...' escape strings
+	ANSI Dialect = iota
+	// MySQL treats backslashes in strings as escapes, as in 'it\'s'
+	MySQL
+)
+
+// DialectString is a string slice with the names of all dialects in order
+var DialectString = []string{
+	"ANSI",
+	"MySQL",
 }
 
 // Operator is between operands in a condition
@@ -84,12 +237,68 @@ type Condition struct {
 	Operand2 string
 	// Operand2IsField determines if Operand2 is a literal or a field name
 	Operand2IsField bool
+	// Operand2Kind is the kind of literal Operand2 is, if it isn't a field name
+	Operand2Kind LiteralKind
+	// Operand2Raw is Operand2 as written, like a Literal's Raw
+	Operand2Raw string
+	// Operand2Expr is the right hand side operand if it's an expression other than a literal or a field name, e.g.
+	// price * 2, in which case Operand2 is empty
+	Operand2Expr Expr
+
+	Position         Position
+	Operand1Position Position
+	Operand2Position Position
+}
+
+// Assignment is a single assignment in the SET clause of an UPDATE, e.g. "count = count + 1", or in a SET statement
+type Assignment struct {
+	// Fields holds the assigned field, or several for a tuple assignment like "(a, b) = (1, 2)"
+	Fields []string
+	// Values holds one expression per field, or for a SET statement every value in e.g. "search_path = a, b"
+	Values []Expr
+
+	Position Position
+	// FieldPositions holds where each of Fields is
+	FieldPositions []Position
+}
+
+// Privilege is a single privilege in a GRANT or REVOKE, e.g. "SELECT" or "UPDATE (a, b)"
+type Privilege struct {
+	// Name is the upper-cased privilege, e.g. "SELECT" or "ALL PRIVILEGES"
+	Name string
+	// Fields holds the columns the privilege is restricted to, if any
+	Fields []string
+}
+
+// Object is something privileges are granted ON, e.g. "TABLE db.t" or "db.*"
+type Object struct {
+	// Kind is e.g. "TABLE" or "SCHEMA", empty if not given
+	Kind string
+	// Database is the qualifying database name, empty if unqualified
+	Database string
+	// Name is the object name, or "*" for every object
+	Name string
+
+	Position Position
+}
+
+// Comment is a comment in the query text, e.g. "-- note", "# note" or "/* note */"
+type Comment struct {
+	// Text is the comment as written, including its delimiters
+	Text string
+	// Start and End are the byte offsets of the comment in the parsed SQL
+	Start int
+	End   int
 }
 
 type Join struct {
 	Type       string
 	Table      string
 	Conditions []JoinCondition
+
+	Position Position
+	// TablePosition is where Table is
+	TablePosition Position
 }
 
 // Condition is a single boolean condition in a WHERE clause
@@ -104,4 +313,9 @@ type JoinCondition struct {
 	Table2 string
 	// Operand1 is the right hand side operand
 	Operand2 string
+
+	Position Position
+	// Operand1Position and Operand2Position are where the operands are, including their table names
+	Operand1Position Position
+	Operand2Position Position
 }
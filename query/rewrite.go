@@ -0,0 +1,352 @@
+package query
+
+import "fmt"
+
+// Rewrite returns a copy of a query tree with every node replaced by f(node), or deleted if f returns nil. Nodes are
+// rewritten bottom up, in the same order as Walk visits them, so f is called on a node after its children have been
+// rewritten. A node whose required child is deleted, e.g. a Condition whose operand is, is deleted too, and deleting a
+// field of a tuple assignment, as in (a, b) = (1, 2), deletes its value, and the other way round.
+//
+// The tree passed in isn't modified: every slice and map of the copy is new, so the copy can be changed freely. f must
+// return a node that can stand where the original did, e.g. a Column or Literal for an operand of a Condition, and a
+// Column or an AliasedExpr of one for a SELECTed field. Rewrite panics otherwise.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	case Query:
+		node = rewriteQuery(n, f)
+	case Join:
+		table := Rewrite(n.table(), f)
+		if table == nil {
+			return nil
+		}
+		n.Table, n.TablePosition = tableName(table.(TableRef)), table.(TableRef).Position
+		n.Conditions = rewriteJoinConditions(n.Conditions, f)
+		node = n
+	case JoinCondition:
+		operand1, operand2 := Rewrite(n.operand1(), f), Rewrite(n.operand2(), f)
+		if operand1 == nil || operand2 == nil {
+			return nil
+		}
+		o1, o2 := operand1.(Column), operand2.(Column)
+		n.Table1, n.Operand1, n.Operand1Position = o1.Table, o1.Name, o1.Position
+		n.Table2, n.Operand2, n.Operand2Position = o2.Table, o2.Name, o2.Position
+		node = n
+	case Condition:
+		operand1, operand2 := Rewrite(n.operand1(), f), Rewrite(n.operand2(), f)
+		if operand1 == nil || operand2 == nil {
+			return nil
+		}
+		switch o := operand1.(type) {
+		case Column:
+			n.Operand1, n.Operand1IsField, n.Operand1Position = columnName(o), true, o.Position
+		case Literal:
+			n.Operand1, n.Operand1IsField, n.Operand1Position = o.Value, false, o.Position
+		default:
+			panic(fmt.Sprintf("query: condition operand rewritten to %T", operand1))
+		}
+		n.Operand2Expr = nil
+		switch o := operand2.(type) {
+		case Column:
+			n.Operand2, n.Operand2IsField, n.Operand2Kind, n.Operand2Raw = columnName(o), true, UnknownLiteral, ""
+			n.Operand2Position = o.Position
+		case Literal:
+			n.Operand2, n.Operand2IsField, n.Operand2Kind, n.Operand2Raw = o.Value, false, o.Kind, o.Raw
+			n.Operand2Position = o.Position
+		case Expr:
+			n.Operand2, n.Operand2IsField, n.Operand2Kind, n.Operand2Raw = "", false, UnknownLiteral, ""
+			n.Operand2Expr = o
+		default:
+			panic(fmt.Sprintf("query: condition operand rewritten to %T", operand2))
+		}
+		node = n
+	case Assignment:
+		rewrittenFields := make([]Node, len(n.Fields))
+		for i := range n.Fields {
+			rewrittenFields[i] = Rewrite(n.field(i), f)
+		}
+		rewrittenValues := make([]Node, len(n.Values))
+		for i, v := range n.Values {
+			rewrittenValues[i] = Rewrite(v, f)
+		}
+		if len(n.Fields) > 1 && len(n.Fields) == len(n.Values) {
+			// the fields and values of a tuple assignment, as in (a, b) = (1, 2), pair up, so they're deleted in pairs
+			for i := range rewrittenFields {
+				if rewrittenFields[i] == nil || rewrittenValues[i] == nil {
+					rewrittenFields[i], rewrittenValues[i] = nil, nil
+				}
+			}
+		}
+		fields, positions := n.Fields, n.FieldPositions
+		if len(n.Fields) > 0 {
+			fields, positions = make([]string, 0, len(n.Fields)), nil
+			if n.FieldPositions != nil {
+				positions = make([]Position, 0, len(n.FieldPositions))
+			}
+			for _, r := range rewrittenFields {
+				if r != nil {
+					fields, positions = appendColumn(fields, positions, r.(Column))
+				}
+			}
+		}
+		values := n.Values
+		if len(n.Values) > 0 {
+			values = make([]Expr, 0, len(n.Values))
+			for _, r := range rewrittenValues {
+				if r != nil {
+					values = append(values, r.(Expr))
+				}
+			}
+		}
+		if (len(n.Fields) > 0 && len(fields) == 0) || (len(n.Values) > 0 && len(values) == 0) {
+			// nothing is left to assign
+			return nil
+		}
+		n.Fields, n.FieldPositions, n.Values = fields, positions, values
+		node = n
+	case AliasedExpr:
+		e := Rewrite(n.Expr, f)
+		if e == nil {
+			return nil
+		}
+		n.Expr = e.(Expr)
+		node = n
+	case BinaryExpr:
+		left, right := Rewrite(n.Left, f), Rewrite(n.Right, f)
+		if left == nil || right == nil {
+			return nil
+		}
+		n.Left, n.Right = left.(Expr), right.(Expr)
+		node = n
+	case UnaryExpr:
+		operand := Rewrite(n.Operand, f)
+		if operand == nil {
+			return nil
+		}
+		n.Operand = operand.(Expr)
+		node = n
+	case FuncCall:
+		n.Args = rewriteExprs(n.Args, f)
+		node = n
+	}
+	return f(node)
+}
+
+func rewriteQuery(q Query, f func(Node) Node) Query {
+	n := q
+	if q.TableName != "" {
+		n.Database, n.TableName, n.TablePosition = "", "", Position{}
+		if table := Rewrite(q.table(), f); table != nil {
+			t := table.(TableRef)
+			n.Database, n.TableName, n.TablePosition = t.Database, t.Name, t.Position
+		}
+	}
+	if len(q.Fields) > 0 {
+		n.Fields, n.FieldPositions = make([]string, 0, len(q.Fields)), nil
+		if q.FieldPositions != nil {
+			n.FieldPositions = make([]Position, 0, len(q.FieldPositions))
+		}
+		if q.Aliases != nil {
+			n.Aliases = make(map[string]string, len(q.Aliases))
+		}
+		for i := range q.Fields {
+			switch r := Rewrite(q.field(i), f).(type) {
+			case nil:
+			case Column:
+				n.Fields, n.FieldPositions = appendColumn(n.Fields, n.FieldPositions, r)
+			case AliasedExpr:
+				c := r.Expr.(Column)
+				n.Fields, n.FieldPositions = appendColumn(n.Fields, n.FieldPositions, c)
+				if n.Aliases == nil {
+					n.Aliases = make(map[string]string)
+				}
+				n.Aliases[columnName(c)] = r.Alias
+			default:
+				panic(fmt.Sprintf("query: field rewritten to %T", r))
+			}
+		}
+	} else {
+		n.FieldPositions = copyPositions(q.FieldPositions)
+		if q.Aliases != nil {
+			n.Aliases = make(map[string]string, len(q.Aliases))
+			for k, v := range q.Aliases {
+				n.Aliases[k] = v
+			}
+		}
+	}
+
+	if len(q.Joins) > 0 {
+		n.Joins = make([]Join, 0, len(q.Joins))
+		for _, j := range q.Joins {
+			if r := Rewrite(j, f); r != nil {
+				n.Joins = append(n.Joins, r.(Join))
+			}
+		}
+	}
+	if len(q.Updates) > 0 {
+		n.Updates = make([]Assignment, 0, len(q.Updates))
+		for _, a := range q.Updates {
+			if r := Rewrite(a, f); r != nil {
+				n.Updates = append(n.Updates, r.(Assignment))
+			}
+		}
+	}
+	if len(q.Inserts) > 0 {
+		n.Inserts = make([][]Expr, len(q.Inserts))
+		for i, row := range q.Inserts {
+			n.Inserts[i] = rewriteExprs(row, f)
+		}
+	}
+	n.From, n.FromPositions = rewriteTables(q, q.From, q.FromPositions, f)
+	n.Using, n.UsingPositions = rewriteTables(q, q.Using, q.UsingPositions, f)
+	n.Targets, n.TargetPositions = rewriteTables(q, q.Targets, q.TargetPositions, f)
+	if len(q.Objects) > 0 {
+		n.Objects = make([]Object, 0, len(q.Objects))
+		for _, o := range q.Objects {
+			if o.isTable() {
+				r := Rewrite(o.table(), f)
+				if r == nil {
+					continue
+				}
+				t := r.(TableRef)
+				o.Database, o.Name, o.Position = t.Database, t.Name, t.Position
+			}
+			n.Objects = append(n.Objects, o)
+		}
+	}
+	if len(q.Privileges) > 0 {
+		n.Privileges = make([]Privilege, len(q.Privileges))
+		for i, p := range q.Privileges {
+			p.Fields = copyStrings(p.Fields)
+			n.Privileges[i] = p
+		}
+	}
+	if len(q.Conditions) > 0 {
+		n.Conditions = make([]Condition, 0, len(q.Conditions))
+		for _, c := range q.Conditions {
+			if r := Rewrite(c, f); r != nil {
+				n.Conditions = append(n.Conditions, r.(Condition))
+			}
+		}
+	}
+	if len(q.OrderFields) > 0 {
+		n.OrderFields, n.OrderDir, n.OrderFieldPositions = make([]string, 0, len(q.OrderFields)), nil, nil
+		if q.OrderDir != nil {
+			n.OrderDir = make([]string, 0, len(q.OrderDir))
+		}
+		if q.OrderFieldPositions != nil {
+			n.OrderFieldPositions = make([]Position, 0, len(q.OrderFieldPositions))
+		}
+		for i := range q.OrderFields {
+			r := Rewrite(q.orderField(i), f)
+			if r == nil {
+				continue
+			}
+			n.OrderFields, n.OrderFieldPositions = appendColumn(n.OrderFields, n.OrderFieldPositions, r.(Column))
+			if i < len(q.OrderDir) {
+				n.OrderDir = append(n.OrderDir, q.OrderDir[i])
+			}
+		}
+	} else {
+		n.OrderDir = copyStrings(q.OrderDir)
+		n.OrderFieldPositions = copyPositions(q.OrderFieldPositions)
+	}
+	n.Returning = rewriteExprs(q.Returning, f)
+	if q.Explained != nil {
+		n.Explained = nil
+		if r := Rewrite(*q.Explained, f); r != nil {
+			explained := r.(Query)
+			n.Explained = &explained
+		}
+	}
+	n.Grantees = copyStrings(q.Grantees)
+	if len(q.Comments) > 0 {
+		n.Comments = append([]Comment(nil), q.Comments...)
+	}
+	if len(q.Hints) > 0 {
+		n.Hints = append([]Comment(nil), q.Hints...)
+	}
+	return n
+}
+
+func rewriteJoinConditions(cs []JoinCondition, f func(Node) Node) []JoinCondition {
+	if len(cs) == 0 {
+		return cs
+	}
+	rewritten := make([]JoinCondition, 0, len(cs))
+	for _, c := range cs {
+		if r := Rewrite(c, f); r != nil {
+			rewritten = append(rewritten, r.(JoinCondition))
+		}
+	}
+	return rewritten
+}
+
+func rewriteExprs(es []Expr, f func(Node) Node) []Expr {
+	if len(es) == 0 {
+		return es
+	}
+	rewritten := make([]Expr, 0, len(es))
+	for _, e := range es {
+		if r := Rewrite(e, f); r != nil {
+			rewritten = append(rewritten, r.(Expr))
+		}
+	}
+	return rewritten
+}
+
+func rewriteTables(q Query, names []string, positions []Position, f func(Node) Node) ([]string, []Position) {
+	if len(names) == 0 {
+		return names, copyPositions(positions)
+	}
+	rewrittenNames, rewrittenPositions := make([]string, 0, len(names)), []Position(nil)
+	if positions != nil {
+		rewrittenPositions = make([]Position, 0, len(positions))
+	}
+	for _, t := range q.tables(names, positions) {
+		if r := Rewrite(t, f); r != nil {
+			rewrittenNames = append(rewrittenNames, tableName(r.(TableRef)))
+			if positions != nil {
+				rewrittenPositions = append(rewrittenPositions, r.(TableRef).Position)
+			}
+		}
+	}
+	return rewrittenNames, rewrittenPositions
+}
+
+// appendColumn appends the name a Column is stored as to names, and its position to positions unless they're nil.
+func appendColumn(names []string, positions []Position, c Column) ([]string, []Position) {
+	if positions != nil {
+		positions = append(positions, c.Position)
+	}
+	return append(names, columnName(c)), positions
+}
+
+// columnName returns the field name a Column is stored as, e.g. inserted.id.
+func columnName(c Column) string {
+	if c.Table == "" {
+		return c.Name
+	}
+	return c.Table + "." + c.Name
+}
+
+// tableName returns the table name a TableRef is stored as, e.g. db.t.
+func tableName(t TableRef) string {
+	if t.Database == "" {
+		return t.Name
+	}
+	return t.Database + "." + t.Name
+}
+
+func copyStrings(s []string) []string {
+	if len(s) == 0 {
+		return s
+	}
+	return append([]string(nil), s...)
+}
+
+func copyPositions(s []Position) []Position {
+	if len(s) == 0 {
+		return s
+	}
+	return append([]Position(nil), s...)
+}
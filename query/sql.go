@@ -0,0 +1,600 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+// keywords are the words that can't be used as bare identifiers, because the parser would read them as keywords
+var keywords = map[string]bool{
+	"SELECT": true, "TOP": true, "INSERT": true, "INTO": true, "VALUES": true, "UPDATE": true, "DELETE": true,
+	"WHERE": true, "FROM": true, "SET": true, "ON": true, "ORDER": true, "BY": true, "ASC": true, "DESC": true,
+	"LEFT": true, "RIGHT": true, "INNER": true, "JOIN": true, "AS": true, "RETURNING": true, "OUTPUT": true,
+	"USING": true, "LIMIT": true, "AND": true, "NULL": true, "TRUE": true, "FALSE": true,
+}
+
+// String returns the query as SQL, like SQL
+func (q Query) String() string {
+	return q.SQL()
+}
+
+// SQL returns the query as SQL text that parses back into the same query. Identifiers are quoted with double quotes
+// if they were quoted, or if they need to be. Comments are put before the query and hints after its first keyword,
+// so their positions aren't kept.
+func (q Query) SQL() string {
+	return writer{Query: q}.sql()
+}
+
+// Clause is one of the clauses a query is written as, e.g. its WHERE clause, as returned by Clauses.
+type Clause struct {
+	// Name is the keyword the clause starts with in upper case, whatever case it's written in, e.g. "SELECT" or
+	// "LEFT JOIN"
+	Name string
+	// Keyword is what the clause starts with as written, e.g. "select /*+ NO_ICP(t) */ top 5" or "left join t"
+	Keyword string
+	// Items follow the keyword, e.g. the fields of a SELECT. They're separated by commas unless they're Conditions.
+	Items []string
+	// Conditions is set for the conditions of WHERE and JOINs, where each item starts with the keyword joining it to
+	// the one before, as in "AND b = 2", and a JOIN's first item with "ON"
+	Conditions bool
+}
+
+// String returns the clause as SQL on a single line.
+func (c Clause) String() string {
+	if len(c.Items) == 0 {
+		return c.Keyword
+	}
+	if c.Conditions {
+		return c.Keyword + " " + strings.Join(c.Items, " ")
+	}
+	return c.Keyword + " " + strings.Join(c.Items, ", ")
+}
+
+// Clauses returns the clauses SQL writes the query as, in order, with their keywords in lower case if lower. SELECT,
+// INSERT, UPDATE and DELETE are made of a clause per keyword, e.g. one per JOIN; EXPLAIN is a clause without the query
+// it explains, and other statements are a single clause. The query's hints are in the first clause, after its first
+// keyword, and its comments in none.
+func (q Query) Clauses(lower bool) []Clause {
+	return writer{Query: q, lower: lower}.clauses()
+}
+
+// writer writes a query as SQL, with its keywords in lower case if lower.
+type writer struct {
+	Query
+	lower bool
+	// maxRows is written for a non-zero MaxRows instead of its value if it's set, e.g. "?"
+	maxRows string
+}
+
+// maxRowsSQL returns the number of rows of a TOP or LIMIT as SQL.
+func (w writer) maxRowsSQL() string {
+	if w.maxRows != "" {
+		return w.maxRows
+	}
+	return strconv.Itoa(w.MaxRows)
+}
+
+// kw returns a keyword, e.g. "ORDER BY", in the writer's case.
+func (w writer) kw(keyword string) string {
+	if w.lower {
+		return strings.ToLower(keyword)
+	}
+	return keyword
+}
+
+func (w writer) sql() string {
+	b := &strings.Builder{}
+	for _, c := range w.Comments {
+		b.WriteString(c.Text)
+		if strings.HasPrefix(c.Text, "--") || strings.HasPrefix(c.Text, "#") {
+			b.WriteString("\n")
+		} else {
+			b.WriteString(" ")
+		}
+	}
+	for i, c := range w.clauses() {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(c.String())
+	}
+	if w.Type == Explain && w.Explained != nil {
+		explained := w
+		explained.Query = *w.Explained
+		b.WriteString(" " + explained.sql())
+	}
+	return b.String()
+}
+
+// clause returns a clause made of a keyword and its items.
+func (w writer) clause(keyword string, items ...string) Clause {
+	return Clause{Name: keyword, Keyword: w.kw(keyword), Items: items}
+}
+
+// first returns the clause a query starts with: its first keyword, where hints go, followed by rest.
+func (w writer) first(keyword, rest string) Clause {
+	c := w.clause(keyword)
+	for _, h := range w.Hints {
+		c.Keyword += " " + h.Text
+	}
+	if rest != "" {
+		c.Keyword += " " + rest
+	}
+	return c
+}
+
+func (w writer) clauses() []Clause {
+	switch w.Type {
+	case Select:
+		rest := ""
+		if w.MaxRows > 0 && !w.Limit {
+			rest = w.kw("TOP") + " " + w.maxRowsSQL()
+		}
+		selectClause := w.first("SELECT", rest)
+		selectClause.Items = w.fieldList()
+		clauses := append([]Clause{selectClause, w.clause("FROM", w.tableSQL())}, w.joins()...)
+		clauses = append(clauses, w.where()...)
+		clauses = append(clauses, w.order()...)
+		if w.Limit {
+			clauses = append(clauses, w.limit()...)
+		}
+		return clauses
+	case Insert:
+		rest := w.kw("INTO") + " " + w.tableSQL()
+		if w.Fields != nil {
+			rest += " (" + w.identifiersSQL(w.Fields) + ")"
+		}
+		clauses := []Clause{w.first("INSERT", rest)}
+		if w.Output {
+			clauses = append(clauses, w.returning()...)
+		}
+		clauses = append(clauses, w.clause("VALUES", w.rowList()...))
+		if len(w.Updates) > 0 {
+			clauses = append(clauses, w.clause("ON DUPLICATE KEY UPDATE", w.assignmentList()...))
+		}
+		if !w.Output {
+			clauses = append(clauses, w.returning()...)
+		}
+		return clauses
+	case Update:
+		clauses := []Clause{w.first("UPDATE", w.tableSQL())}
+		if len(w.From) == 0 {
+			clauses = append(clauses, w.joins()...)
+		}
+		clauses = append(clauses, w.clause("SET", w.assignmentList()...))
+		if w.Output {
+			// T-SQL's OUTPUT comes before FROM
+			clauses = append(clauses, w.returning()...)
+		}
+		if len(w.From) > 0 {
+			clauses = append(clauses, w.clause("FROM", w.identifierList(w.From)...))
+			clauses = append(clauses, w.joins()...)
+		}
+		return append(clauses, w.dmlTail()...)
+	case Delete:
+		rest := w.kw("FROM") + " " + w.tableSQL()
+		if len(w.Targets) > 0 {
+			rest = w.identifiersSQL(w.Targets) + " " + rest
+		}
+		clauses := []Clause{w.first("DELETE", rest)}
+		if w.Output {
+			clauses = append(clauses, w.returning()...)
+		}
+		if len(w.Using) > 0 {
+			clauses = append(clauses, w.clause("USING", w.identifierList(w.Using)...))
+		}
+		clauses = append(clauses, w.joins()...)
+		return append(clauses, w.dmlTail()...)
+	case Explain:
+		rest := ""
+		if w.Analyze {
+			rest = w.kw("ANALYZE")
+		}
+		return []Clause{w.first("EXPLAIN", rest)}
+	}
+	keyword, rest := w.statementParts()
+	if keyword == "" {
+		return nil
+	}
+	return []Clause{w.first(keyword, rest)}
+}
+
+// statementParts returns a statement other than SELECT, INSERT, UPDATE, DELETE and EXPLAIN as SQL, split after its
+// first keyword.
+func (w writer) statementParts() (string, string) {
+	switch w.Type {
+	case Begin:
+		return "BEGIN", ""
+	case Commit:
+		return "COMMIT", ""
+	case Rollback:
+		if w.Savepoint != "" {
+			return "ROLLBACK", w.kw("TO SAVEPOINT") + " " + w.identifierSQL(w.Savepoint)
+		}
+		return "ROLLBACK", ""
+	case Savepoint:
+		return "SAVEPOINT", w.identifierSQL(w.Savepoint)
+	case Release:
+		return "RELEASE", w.kw("SAVEPOINT") + " " + w.identifierSQL(w.Savepoint)
+	case Set:
+		rest := ""
+		if w.Scope != "" {
+			rest = w.kw(w.Scope) + " "
+		}
+		assignments := make([]string, len(w.Updates))
+		for i, u := range w.Updates {
+			if strings.ToUpper(u.Fields[0]) == "NAMES" {
+				// MySQL's "SET NAMES utf8" has no "="
+				assignments[i] = w.kw("NAMES") + " " + w.exprsSQL(u.Values)
+				continue
+			}
+			assignments[i] = w.identifierSQL(u.Fields[0]) + " = " + w.exprsSQL(u.Values)
+		}
+		return "SET", rest + strings.Join(assignments, ", ")
+	case Use:
+		return "USE", w.identifierSQL(w.Database)
+	case Show:
+		switch w.Show {
+		case "TABLES", "DATABASES", "SCHEMAS":
+			return "SHOW", w.kw(w.Show) + w.showDatabaseSQL()
+		case "COLUMNS", "FIELDS", "INDEX", "INDEXES", "KEYS":
+			return "SHOW", w.kw(w.Show) + " " + w.kw("FROM") + " " + w.identifierSQL(w.TableName) + w.showDatabaseSQL()
+		case "CREATE TABLE":
+			return "SHOW", w.kw(w.Show) + " " + w.tableSQL()
+		}
+		// a setting's name, e.g. SHOW search_path
+		return "SHOW", w.Show + w.showDatabaseSQL()
+	case Describe:
+		return "DESCRIBE", w.tableSQL()
+	case Grant, Revoke:
+		keyword, rest, to := "GRANT", "", "TO"
+		if w.Type == Revoke {
+			keyword, to = "REVOKE", "FROM"
+			if w.GrantOption {
+				rest = w.kw("GRANT OPTION FOR") + " "
+			}
+		}
+		grantees := make([]string, len(w.Grantees))
+		for i, g := range w.Grantees {
+			if at := strings.LastIndex(g, "@"); at >= 0 {
+				// a MySQL account, 'user'@'host'
+				grantees[i] = w.stringSQL(g[:at]) + "@" + w.stringSQL(g[at+1:])
+				continue
+			}
+			grantees[i] = w.identifierSQL(g)
+		}
+		rest += strings.Join(w.privilegeList(), ", ") + " " + w.kw("ON") + " " + strings.Join(w.objectList(), ", ") + " " +
+			w.kw(to) + " " + strings.Join(grantees, ", ")
+		if w.Type == Grant && w.GrantOption {
+			rest += " " + w.kw("WITH GRANT OPTION")
+		}
+		return keyword, rest
+	}
+	return "", ""
+}
+
+// dmlTail returns the clauses ending an UPDATE or DELETE: WHERE, ORDER BY, LIMIT and RETURNING.
+func (w writer) dmlTail() []Clause {
+	clauses := append(w.where(), w.order()...)
+	clauses = append(clauses, w.limit()...)
+	if !w.Output {
+		clauses = append(clauses, w.returning()...)
+	}
+	return clauses
+}
+
+func (q Query) tableSQL() string {
+	if q.Database != "" {
+		return q.identifierSQL(q.Database) + "." + q.identifierSQL(q.TableName)
+	}
+	return q.identifierSQL(q.TableName)
+}
+
+func (w writer) showDatabaseSQL() string {
+	if w.Database == "" {
+		return ""
+	}
+	return " " + w.kw("FROM") + " " + w.identifierSQL(w.Database)
+}
+
+func (w writer) joins() []Clause {
+	clauses := make([]Clause, len(w.Joins))
+	for i, j := range w.Joins {
+		keyword := w.kw(j.Type) + " " + w.identifierSQL(j.Table)
+		clauses[i] = Clause{Name: strings.ToUpper(j.Type), Keyword: keyword, Conditions: true}
+		for k, c := range j.Conditions {
+			conjunction := "AND"
+			if k == 0 {
+				conjunction = "ON"
+			}
+			clauses[i].Items = append(clauses[i].Items, w.kw(conjunction)+" "+w.joinConditionSQL(c))
+		}
+	}
+	return clauses
+}
+
+func (w writer) where() []Clause {
+	if len(w.Conditions) == 0 {
+		return nil
+	}
+	conditions := w.conditionList()
+	for i := 1; i < len(conditions); i++ {
+		conditions[i] = w.kw("AND") + " " + conditions[i]
+	}
+	where := w.clause("WHERE", conditions...)
+	where.Conditions = true
+	return []Clause{where}
+}
+
+func (w writer) order() []Clause {
+	if len(w.OrderFields) == 0 {
+		return nil
+	}
+	return []Clause{w.clause("ORDER BY", w.orderList()...)}
+}
+
+func (w writer) limit() []Clause {
+	if w.MaxRows == 0 {
+		return nil
+	}
+	return []Clause{w.clause("LIMIT", w.maxRowsSQL())}
+}
+
+func (w writer) returning() []Clause {
+	if len(w.Returning) == 0 {
+		return nil
+	}
+	if w.Output {
+		return []Clause{w.clause("OUTPUT", w.exprList(w.Returning)...)}
+	}
+	return []Clause{w.clause("RETURNING", w.exprList(w.Returning)...)}
+}
+
+func (w writer) assignmentSQL(a Assignment) string {
+	if len(a.Fields) == 1 {
+		return w.identifierSQL(a.Fields[0]) + " = " + w.exprsSQL(a.Values)
+	}
+	return "(" + w.identifiersSQL(a.Fields) + ") = (" + w.exprsSQL(a.Values) + ")"
+}
+
+// conditionSQL returns a condition of the query's WHERE clause as SQL, e.g. "a = '1'"
+func (q Query) conditionSQL(c Condition) string {
+	operand1 := c.Operand1
+	if c.Operand1IsField {
+		operand1 = q.identifierSQL(operand1)
+	}
+	return operand1 + " " + operatorSQL(c.Operator) + " " + q.ExprSQL(c.operand2())
+}
+
+// joinConditionSQL returns a condition of one of the query's JOINs as SQL, e.g. "a.id = b.a_id"
+func (q Query) joinConditionSQL(c JoinCondition) string {
+	return q.identifierSQL(c.Table1) + "." + q.identifierPartSQL(c.Operand1) + " " + operatorSQL(c.Operator) + " " +
+		q.identifierSQL(c.Table2) + "." + q.identifierPartSQL(c.Operand2)
+}
+
+func operatorSQL(o Operator) string {
+	switch o {
+	case Eq:
+		return "="
+	case Ne:
+		return "!="
+	case Gt:
+		return ">"
+	case Lt:
+		return "<"
+	case Gte:
+		return ">="
+	case Lte:
+		return "<="
+	}
+	return "?"
+}
+
+func (w writer) exprsSQL(es []Expr) string {
+	return strings.Join(w.exprList(es), ", ")
+}
+
+func (q Query) identifiersSQL(names []string) string {
+	return strings.Join(q.identifierList(names), ", ")
+}
+
+func (w writer) fieldList() []string {
+	fields := make([]string, len(w.Fields))
+	for i, f := range w.Fields {
+		fields[i] = w.identifierSQL(f)
+		if alias, ok := w.Aliases[f]; ok {
+			fields[i] += " " + w.kw("AS") + " " + w.identifierSQL(alias)
+		}
+	}
+	return fields
+}
+
+func (w writer) joinList() []string {
+	joins := make([]string, len(w.Joins))
+	for i, j := range w.joins() {
+		joins[i] = j.String()
+	}
+	return joins
+}
+
+func (w writer) assignmentList() []string {
+	assignments := make([]string, len(w.Updates))
+	for i, u := range w.Updates {
+		assignments[i] = w.assignmentSQL(u)
+	}
+	return assignments
+}
+
+func (w writer) rowList() []string {
+	rows := make([]string, len(w.Inserts))
+	for i, row := range w.Inserts {
+		rows[i] = "(" + w.exprsSQL(row) + ")"
+	}
+	return rows
+}
+
+func (q Query) identifierList(names []string) []string {
+	identifiers := make([]string, len(names))
+	for i, name := range names {
+		identifiers[i] = q.identifierSQL(name)
+	}
+	return identifiers
+}
+
+func (q Query) conditionList() []string {
+	conditions := make([]string, len(q.Conditions))
+	for i, c := range q.Conditions {
+		conditions[i] = q.conditionSQL(c)
+	}
+	return conditions
+}
+
+func (w writer) orderList() []string {
+	fields := make([]string, len(w.OrderFields))
+	for i, f := range w.OrderFields {
+		fields[i] = w.identifierSQL(f)
+		if i < len(w.OrderDir) && w.OrderDir[i] == "DESC" {
+			fields[i] += " " + w.kw("DESC")
+		}
+	}
+	return fields
+}
+
+func (w writer) exprList(es []Expr) []string {
+	sqls := make([]string, len(es))
+	for i, e := range es {
+		sqls[i] = w.exprSQL(e)
+	}
+	return sqls
+}
+
+func (w writer) privilegeList() []string {
+	privileges := make([]string, len(w.Privileges))
+	for i, p := range w.Privileges {
+		privileges[i] = w.kw(p.Name)
+		if len(p.Fields) > 0 {
+			privileges[i] += " (" + w.identifiersSQL(p.Fields) + ")"
+		}
+	}
+	return privileges
+}
+
+func (w writer) objectList() []string {
+	objects := make([]string, len(w.Objects))
+	for i, o := range w.Objects {
+		if o.Kind != "" {
+			objects[i] = w.kw(o.Kind) + " "
+		}
+		if o.Database != "" {
+			objects[i] += w.identifierSQL(o.Database) + "."
+		}
+		objects[i] += w.identifierSQL(o.Name)
+	}
+	return objects
+}
+
+// ExprSQL returns an expression of the query as SQL, e.g. "price * (qty - 2)"
+func (q Query) ExprSQL(e Expr) string {
+	return writer{Query: q}.exprSQL(e)
+}
+
+func (w writer) exprSQL(e Expr) string {
+	switch e := e.(type) {
+	case Column:
+		if e.Table == "" {
+			return w.identifierPartSQL(e.Name)
+		}
+		return w.identifierSQL(e.Table) + "." + w.identifierPartSQL(e.Name)
+	case AliasedExpr:
+		return w.exprSQL(e.Expr) + " " + w.kw("AS") + " " + w.identifierSQL(e.Alias)
+	case Literal:
+		if e.Raw != "" {
+			return e.Raw
+		}
+		if e.Kind == StringLiteral {
+			return w.stringSQL(e.Value)
+		}
+		return e.Value
+	case BinaryExpr:
+		precedence := operatorPrecedence(e.Operator)
+		left, right := w.exprSQL(e.Left), w.exprSQL(e.Right)
+		// operators are left-associative, so only the right operand needs parens at the same precedence
+		if l, ok := e.Left.(BinaryExpr); ok && operatorPrecedence(l.Operator) < precedence {
+			left = "(" + left + ")"
+		}
+		if r, ok := e.Right.(BinaryExpr); ok && operatorPrecedence(r.Operator) <= precedence {
+			right = "(" + right + ")"
+		}
+		return left + " " + e.Operator + " " + right
+	case UnaryExpr:
+		operand := w.exprSQL(e.Operand)
+		if _, ok := e.Operand.(BinaryExpr); ok {
+			return e.Operator + "(" + operand + ")"
+		}
+		if strings.HasPrefix(operand, "-") {
+			// "--" would start a comment
+			return e.Operator + " " + operand
+		}
+		return e.Operator + operand
+	case FuncCall:
+		if e.Name == "VALUES" {
+			// MySQL's VALUES(a), which isn't a function name that needs quotes
+			return w.kw("VALUES") + "(" + w.exprsSQL(e.Args) + ")"
+		}
+		return w.identifierSQL(e.Name) + "(" + w.exprsSQL(e.Args) + ")"
+	}
+	return ""
+}
+
+func operatorPrecedence(operator string) int {
+	switch operator {
+	case "*", "/", "%":
+		return 2
+	}
+	return 1
+}
+
+// identifierSQL returns a possibly qualified identifier of the query as SQL, e.g. `db."my table"`, quoting the parts
+// that were quoted or need to be.
+func (q Query) identifierSQL(name string) string {
+	parts := nameParts(name)
+	for i, part := range parts {
+		parts[i] = q.identifierPartSQL(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// identifierPartSQL returns a single unqualified identifier as SQL. MySQL variables like @x are written as they are.
+func (q Query) identifierPartSQL(name string) string {
+	if name == "*" || isQuoted(name) || strings.HasPrefix(name, "@") || !needsQuotes(name) {
+		return name
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// isQuoted reports whether a name part was quoted, e.g. "Foo", as it's then kept with its quotes.
+func isQuoted(name string) bool {
+	return len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"'
+}
+
+func needsQuotes(name string) bool {
+	if name == "" || keywords[strings.ToUpper(name)] || (name[0] >= '0' && name[0] <= '9') {
+		return true
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return true
+		}
+	}
+	return false
+}
+
+// stringSQL returns a string as a literal of the query's dialect, doubling its quotes, and its backslashes in MySQL,
+// which reads them as escapes.
+func (q Query) stringSQL(s string) string {
+	if q.Dialect == MySQL {
+		s = strings.Replace(s, `\`, `\\`, -1)
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
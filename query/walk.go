@@ -0,0 +1,268 @@
+package query
+
+import "strings"
+
+// Node is a node of a query tree, as visited by Walk: a Query, TableRef, Join, JoinCondition, Condition, Assignment
+// or Expr. The tables and columns a Query names as strings, and the literals of its conditions, are visited as the
+// TableRef, Column and Literal nodes they stand for.
+type Node interface {
+	node()
+}
+
+// TableRef is a reference to a table, e.g. "db.t" in "SELECT a FROM db.t"
+type TableRef struct {
+	// Database is the qualifying database name, empty if unqualified
+	Database string
+	Name     string
+
+	Position Position
+}
+
+func (Query) node()         {}
+func (TableRef) node()      {}
+func (Join) node()          {}
+func (JoinCondition) node() {}
+func (Condition) node()     {}
+func (Assignment) node()    {}
+func (Column) node()        {}
+func (AliasedExpr) node()   {}
+func (Literal) node()       {}
+func (BinaryExpr) node()    {}
+func (UnaryExpr) node()     {}
+func (FuncCall) node()      {}
+
+// A Visitor's Visit method is called by Walk for each node. If it returns a non-nil visitor w, Walk visits each of the
+// node's children with w, then calls w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a query tree depth-first, calling v.Visit(node) first. A Query's children are visited in the order
+// its clauses are usually written: its table, fields, joins, assignments, inserted rows, FROM, USING and DELETE
+// target tables, the tables privileges are granted on, conditions, ORDER BY fields, RETURNING expressions and
+// explained query.
+func Walk(node Node, v Visitor) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	switch n := node.(type) {
+	case Query:
+		if n.TableName != "" {
+			Walk(n.table(), v)
+		}
+		for i := range n.Fields {
+			Walk(n.field(i), v)
+		}
+		for _, j := range n.Joins {
+			Walk(j, v)
+		}
+		for _, a := range n.Updates {
+			Walk(a, v)
+		}
+		for _, row := range n.Inserts {
+			walkExprs(row, v)
+		}
+		for _, t := range n.tables(n.From, n.FromPositions) {
+			Walk(t, v)
+		}
+		for _, t := range n.tables(n.Using, n.UsingPositions) {
+			Walk(t, v)
+		}
+		for _, t := range n.tables(n.Targets, n.TargetPositions) {
+			Walk(t, v)
+		}
+		for _, o := range n.Objects {
+			if o.isTable() {
+				Walk(o.table(), v)
+			}
+		}
+		for _, c := range n.Conditions {
+			Walk(c, v)
+		}
+		for i := range n.OrderFields {
+			Walk(n.orderField(i), v)
+		}
+		walkExprs(n.Returning, v)
+		if n.Explained != nil {
+			Walk(*n.Explained, v)
+		}
+	case Join:
+		Walk(n.table(), v)
+		for _, c := range n.Conditions {
+			Walk(c, v)
+		}
+	case JoinCondition:
+		Walk(n.operand1(), v)
+		Walk(n.operand2(), v)
+	case Condition:
+		Walk(n.operand1(), v)
+		Walk(n.operand2(), v)
+	case Assignment:
+		for i := range n.Fields {
+			Walk(n.field(i), v)
+		}
+		walkExprs(n.Values, v)
+	case AliasedExpr:
+		Walk(n.Expr, v)
+	case BinaryExpr:
+		Walk(n.Left, v)
+		Walk(n.Right, v)
+	case UnaryExpr:
+		Walk(n.Operand, v)
+	case FuncCall:
+		walkExprs(n.Args, v)
+	}
+	v.Visit(nil)
+}
+
+func walkExprs(es []Expr, v Visitor) {
+	for _, e := range es {
+		Walk(e, v)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a query tree in the same order as Walk, calling f(node) for each node, and f(nil) after a node's
+// children. If f returns false, the node's children aren't visited.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
+
+// column returns the Column a field name stands for, e.g. inserted.id, taking its last dot outside quotes to
+// qualify it with its table.
+func column(name string) Column {
+	if parts := nameParts(name); len(parts) > 1 {
+		return Column{Table: strings.Join(parts[:len(parts)-1], "."), Name: parts[len(parts)-1]}
+	}
+	return Column{Name: name}
+}
+
+// tableRef returns the TableRef a table name stands for, e.g. db.t, taking its first dot outside quotes to qualify
+// it with its database.
+func tableRef(name string) TableRef {
+	if parts := nameParts(name); len(parts) > 1 {
+		return TableRef{Database: parts[0], Name: strings.Join(parts[1:], ".")}
+	}
+	return TableRef{Name: name}
+}
+
+// nameParts splits a possibly qualified name at the dots outside its quoted parts, e.g. t and "a.b" for t."a.b".
+func nameParts(name string) []string {
+	parts := []string{}
+	start, quoted := 0, false
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '"':
+			// a doubled quote, as in "a""b", toggles twice
+			quoted = !quoted
+		case name[i] == '.' && !quoted:
+			parts = append(parts, name[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, name[start:])
+}
+
+// table returns the TableRef the query's table name stands for.
+func (q Query) table() TableRef {
+	return TableRef{Database: q.Database, Name: q.TableName, Position: q.TablePosition}
+}
+
+// field returns the node the query's i-th field stands for, a Column or an AliasedExpr of one.
+func (q Query) field(i int) Expr {
+	c := column(q.Fields[i])
+	c.Position = positionAt(q.FieldPositions, i)
+	if alias, ok := q.Aliases[q.Fields[i]]; ok {
+		return AliasedExpr{Expr: c, Alias: alias, Position: c.Position}
+	}
+	return c
+}
+
+// orderField returns the Column the query's i-th ORDER BY field stands for.
+func (q Query) orderField(i int) Column {
+	c := column(q.OrderFields[i])
+	c.Position = positionAt(q.OrderFieldPositions, i)
+	return c
+}
+
+// tables returns the TableRefs table names of the query stand for, given their positions.
+func (q Query) tables(names []string, positions []Position) []TableRef {
+	tables := make([]TableRef, len(names))
+	for i, name := range names {
+		tables[i] = tableRef(name)
+		tables[i].Position = positionAt(positions, i)
+	}
+	return tables
+}
+
+// table returns the TableRef the joined table name stands for.
+func (j Join) table() TableRef {
+	t := tableRef(j.Table)
+	t.Position = j.TablePosition
+	return t
+}
+
+// operand1 returns the Column the left hand side of the join condition stands for.
+func (c JoinCondition) operand1() Column {
+	return Column{Table: c.Table1, Name: c.Operand1, Position: c.Operand1Position}
+}
+
+// operand2 returns the Column the right hand side of the join condition stands for.
+func (c JoinCondition) operand2() Column {
+	return Column{Table: c.Table2, Name: c.Operand2, Position: c.Operand2Position}
+}
+
+// field returns the Column the assignment's i-th field stands for.
+func (a Assignment) field(i int) Column {
+	c := column(a.Fields[i])
+	c.Position = positionAt(a.FieldPositions, i)
+	return c
+}
+
+// isTable reports whether privileges are granted on the object as a table.
+func (o Object) isTable() bool {
+	return (o.Kind == "" || o.Kind == "TABLE") && o.Name != "*"
+}
+
+// table returns the TableRef the object stands for, if it's a table.
+func (o Object) table() TableRef {
+	return TableRef{Database: o.Database, Name: o.Name, Position: o.Position}
+}
+
+// operand1 returns the node standing for the left hand side of the condition.
+func (c Condition) operand1() Expr {
+	if c.Operand1IsField {
+		col := column(c.Operand1)
+		col.Position = c.Operand1Position
+		return col
+	}
+	return Literal{Value: c.Operand1, Position: c.Operand1Position}
+}
+
+// operand2 returns the node standing for the right hand side of the condition.
+func (c Condition) operand2() Expr {
+	if c.Operand2Expr != nil {
+		return c.Operand2Expr
+	}
+	if c.Operand2IsField {
+		col := column(c.Operand2)
+		col.Position = c.Operand2Position
+		return col
+	}
+	return Literal{Kind: c.Operand2Kind, Value: c.Operand2, Raw: c.Operand2Raw, Position: c.Operand2Position}
+}
+
+func positionAt(positions []Position, i int) Position {
+	if i < len(positions) {
+		return positions[i]
+	}
+	return Position{}
+}
@@ -0,0 +1,153 @@
+package sqlparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spasticus74/sqlparser/query"
+)
+
+// DefaultMaxInsertSize is the default StatementReader.MaxInsertSize.
+const DefaultMaxInsertSize = 1 << 20
+
+const readSize = 64 << 10
+
+// StatementReader reads statements one at a time from a script, e.g. a database dump, splitting them the same way
+// ParseScript does. It only buffers the statement being read, so a multi-row "INSERT ... VALUES" larger than
+// MaxInsertSize is returned in batches of rows, each an INSERT of its own with the statement's table and fields.
+// Clauses after the rows, such as RETURNING or ON DUPLICATE KEY UPDATE, are only kept on the last batch.
+type StatementReader struct {
+	// MaxInsertSize is the size in bytes at which a multi-row INSERT is cut into batches, at the end of a row, or 0 not
+	// to cut INSERTs
+	MaxInsertSize int
+	Dialect       Dialect
+
+	r      io.Reader
+	chunk  []byte
+	data   strings.Builder // what's been read, ending with buf
+	buf    string          // what's been read and not returned yet
+	header string          // the "INSERT ... VALUES " the rows in buf belong to, once the INSERT has been cut
+	eof    bool
+	err    error
+	split  *splitter
+	line   int // line of the start of buf
+}
+
+// NewStatementReader returns a StatementReader reading from r.
+func NewStatementReader(r io.Reader) *StatementReader {
+	return &StatementReader{MaxInsertSize: DefaultMaxInsertSize, r: r, split: newSplitter(ANSI), line: 1}
+}
+
+// Next parses and returns the next statement. It returns io.EOF when there are no more statements. A statement that
+// fails to parse makes Next return its error, prefixed with its line, and the following call carries on with the next
+// statement; an error reading makes every following call fail.
+func (sr *StatementReader) Next() (query.Query, error) {
+	for {
+		if sr.err != nil {
+			return query.Query{}, sr.err
+		}
+		if sr.split.full {
+			q, cut, more, err := sr.nextBatch()
+			if cut {
+				return q, err
+			}
+			if more && !sr.eof {
+				sr.read()
+				continue
+			}
+			sr.split.full = false
+		}
+		sr.split.dialect, sr.split.batchSize = sr.Dialect, sr.MaxInsertSize
+		start, end, consumed, ok := sr.split.next(sr.buf, sr.eof)
+		if ok {
+			line := sr.line + strings.Count(sr.buf[:start], "\n")
+			stmt := sr.header + sr.buf[start:end]
+			sr.header = ""
+			sr.line += strings.Count(sr.buf[:consumed], "\n")
+			sr.buf = sr.buf[consumed:]
+			return sr.parse(stmt, line)
+		}
+		if sr.split.full {
+			continue
+		}
+		if sr.eof {
+			sr.buf = ""
+			return query.Query{}, io.EOF
+		}
+		sr.read()
+	}
+}
+
+// nextBatch cuts the rows read so far off an INSERT that's grown to MaxInsertSize, if it can, or reports whether
+// more must be read to tell.
+func (sr *StatementReader) nextBatch() (q query.Query, cut bool, more bool, err error) {
+	s := sr.split
+	if sr.header == "" && strings.ToUpper(sr.buf[s.code:s.code+6]) != "INSERT" {
+		return query.Query{}, false, false, nil
+	}
+	// the next row must follow, as in "(1, 2), (3, 4)"
+	next := s.rowEnd
+	for next < len(sr.buf) && isSpace(sr.buf[next]) {
+		next++
+	}
+	if next == len(sr.buf) {
+		return query.Query{}, false, true, nil
+	}
+	if sr.buf[next] != ',' {
+		return query.Query{}, false, false, nil
+	}
+	next++
+	for next < len(sr.buf) && isSpace(sr.buf[next]) {
+		next++
+	}
+	if next == len(sr.buf) {
+		return query.Query{}, false, true, nil
+	}
+	if sr.buf[next] != '(' {
+		return query.Query{}, false, false, nil
+	}
+
+	line := sr.line + strings.Count(sr.buf[:s.start], "\n")
+	batch := sr.header + sr.buf[s.start:s.rowEnd]
+	if sr.header == "" {
+		// leading comments only go with the first batch
+		sr.header = sr.buf[s.code:s.values] + " "
+	}
+	sr.line += strings.Count(sr.buf[:next], "\n")
+	sr.buf = sr.buf[next:]
+	// the splitter carries on from the next row, as if it came right after VALUES
+	s.i, s.start, s.code, s.values, s.rowEnd, s.full = 0, 0, 0, 0, -1, false
+	q, err = sr.parse(batch, line)
+	return q, true, false, err
+}
+
+// read appends the next chunk read to buf. What's been returned is dropped from data once it's at least half of it,
+// so that neither appending nor dropping copies more than linearly.
+func (sr *StatementReader) read() {
+	if sr.chunk == nil {
+		sr.chunk = make([]byte, readSize)
+	}
+	if len(sr.buf) <= sr.data.Len()/2 {
+		rest := sr.buf
+		sr.data.Reset()
+		sr.data.WriteString(rest)
+	}
+	n, err := sr.r.Read(sr.chunk)
+	sr.data.Write(sr.chunk[:n])
+	data := sr.data.String()
+	sr.buf = data[len(data)-len(sr.buf)-n:]
+	if err == io.EOF {
+		sr.eof = true
+	} else if err != nil {
+		sr.err = err
+	}
+}
+
+func (sr *StatementReader) parse(stmt string, line int) (query.Query, error) {
+	q, err := ParseOpts(stmt, ParseOptions{Dialect: sr.Dialect})
+	if err != nil {
+		return q, fmt.Errorf("at line %v: %v", line, err)
+	}
+	return q, nil
+}
@@ -0,0 +1,339 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spasticus74/sqlparser/query"
+)
+
+// Statement is a single statement of a script parsed by ParseScript.
+type Statement struct {
+	Query query.Query
+	// SQL is the statement's text, without its terminating delimiter. Positions within Query are relative to it.
+	SQL string
+	// Start and End are the byte offsets of SQL within the script
+	Start int
+	End   int
+	// Err is the error parsing the statement, if any
+	Err error
+}
+
+// ScriptOptions configures ParseScriptOpts.
+type ScriptOptions struct {
+	// ContinueOnError makes parsing carry on past statements that fail to parse, instead of stopping at the first one.
+	ContinueOnError bool
+	Dialect         Dialect
+}
+
+// ParseScript takes a string with many SQL statements separated by semicolons and parses each of them. Semicolons in
+// strings, quoted identifiers, comments and dollar-quoted bodies don't separate statements, and the delimiter can be
+// changed with the MySQL client's "DELIMITER" command. It may fail. If it fails, it will stop at the first failure.
+func ParseScript(sqls string) ([]Statement, error) {
+	return ParseScriptOpts(sqls, ScriptOptions{})
+}
+
+// ParseScriptOpts is like ParseScript, with options. The returned error is that of the first statement that failed to
+// parse; with ContinueOnError, every statement is returned, each with its own Err.
+func ParseScriptOpts(sqls string, opts ScriptOptions) ([]Statement, error) {
+	stmts := []Statement{}
+	var firstErr error
+	s := newSplitter(opts.Dialect)
+	for offset := 0; offset < len(sqls); {
+		start, end, consumed, ok := s.next(sqls[offset:], true)
+		if !ok {
+			break
+		}
+		stmt := Statement{SQL: sqls[offset+start : offset+end], Start: offset + start, End: offset + end}
+		stmt.Query, stmt.Err = ParseOpts(stmt.SQL, ParseOptions{Dialect: opts.Dialect})
+		offset += consumed
+		if stmt.Err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("at line %v: %v", strings.Count(sqls[:stmt.Start], "\n")+1, stmt.Err)
+			}
+			if !opts.ContinueOnError {
+				return stmts, firstErr
+			}
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, firstErr
+}
+
+type splitState int
+
+const (
+	splitCode splitState = iota
+	splitSingleQuote
+	splitDoubleQuote
+	splitBacktick
+	splitBracket
+	splitLineComment
+	splitBlockComment
+	splitDollarQuote
+)
+
+// splitter finds where statements end in a script, keeping track of quotes, comments and the current delimiter.
+// It can be fed a growing buffer, resuming where it stopped.
+type splitter struct {
+	dialect   Dialect
+	delimiter string
+	state     splitState
+	escapes   bool   // whether backslashes escape in the current single-quoted string
+	tag       string // the closing tag of the current dollar-quoted string, e.g. "$body$"
+	i         int    // scan position in the buffer
+	start     int    // start of the current statement in the buffer, -1 if it hasn't started
+	code      int    // start of the current statement past its leading comments, -1 if it hasn't started
+	content   bool   // whether the current statement has anything but whitespace and comments
+	depth     int    // parens nesting depth
+	values    int    // end of the statement's first top-level VALUES keyword, -1 if there's none yet
+	rowEnd    int    // end of the last top-level closing parens after values, -1 if there's none yet
+	batchSize int    // size of a statement past which next stops at the end of each row, or 0 not to
+	full      bool   // set when next stopped at the end of a row because the statement is past batchSize
+}
+
+func newSplitter(dialect Dialect) *splitter {
+	return &splitter{dialect: dialect, delimiter: ";", start: -1, code: -1, values: -1, rowEnd: -1}
+}
+
+// next scans buf for the end of the next statement. buf must start right after the bytes consumed by the last
+// statement found, and if next returned !ok, extend the buf it was last given. When a statement is found, it's
+// buf[start:end], and the caller must drop the first consumed bytes of buf before calling next again. Unless atEOF,
+// next returns !ok when it needs more of the buffer to decide. It also returns !ok, setting full, at the end of each
+// row of VALUES once the statement is past batchSize, so that the caller can cut the rows off.
+func (s *splitter) next(buf string, atEOF bool) (start, end, consumed int, ok bool) {
+	for s.i < len(buf) {
+		c := buf[s.i]
+		switch s.state {
+		case splitCode:
+			if s.start < 0 {
+				if isSpace(c) {
+					s.i++
+					continue
+				}
+				s.start = s.i
+			}
+			if !s.content && (c == 'D' || c == 'd') {
+				delimiter, lineEnd, more := delimiterCommand(buf[s.i:], atEOF)
+				if more {
+					return 0, 0, 0, false
+				}
+				if delimiter != "" {
+					s.delimiter = delimiter
+					s.i += lineEnd
+					s.start = -1
+					continue
+				}
+			}
+			if matched, more := hasPrefix(buf, s.i, s.delimiter, atEOF); more {
+				return 0, 0, 0, false
+			} else if matched {
+				if !s.content {
+					// an empty statement, e.g. ";;" or a comment on its own
+					s.i += len(s.delimiter)
+					s.start = -1
+					continue
+				}
+				return s.found(buf, s.i, s.i+len(s.delimiter))
+			}
+			at := s.i
+			switch {
+			case c == '(':
+				s.depth++
+			case c == ')':
+				s.depth--
+				if s.depth == 0 && s.values >= 0 {
+					s.rowEnd = s.i + 1
+					if s.batchSize > 0 && s.rowEnd-s.start >= s.batchSize {
+						s.i++
+						s.full = true
+						return 0, 0, 0, false
+					}
+				}
+			case (c == 'V' || c == 'v') && s.values < 0 && s.depth == 0 && (s.i == 0 || !isIdentifierChar(buf[s.i-1])):
+				word := buf[s.i:]
+				if len(word) > 6 {
+					word = word[:6]
+				}
+				matched, more := hasPrefix(strings.ToUpper(word), 0, "VALUES", atEOF)
+				if more || (matched && s.i+6 == len(buf) && !atEOF) {
+					return 0, 0, 0, false
+				}
+				if matched && (s.i+6 == len(buf) || !isIdentifierChar(buf[s.i+6])) {
+					s.values = s.i + 6
+				}
+			case c == '\'':
+				s.state = splitSingleQuote
+				// as in the parser, backslashes escape in MySQL, and in E'...' strings elsewhere
+				s.escapes = s.dialect == MySQL || (s.i > 0 && toUpper(buf[s.i-1]) == 'E' &&
+					(s.i == 1 || !isIdentifierChar(buf[s.i-2])))
+			case c == '"':
+				s.state = splitDoubleQuote
+			case c == '`':
+				s.state = splitBacktick
+			case c == '[':
+				s.state = splitBracket
+			case c == '#':
+				s.state = splitLineComment
+			case c == '-' || c == '/':
+				next, more := peekByte(buf, s.i+1, atEOF)
+				if more {
+					return 0, 0, 0, false
+				}
+				if c == '-' && next == '-' {
+					s.state = splitLineComment
+				} else if c == '/' && next == '*' {
+					s.state = splitBlockComment
+					s.i++
+				}
+			case c == '$':
+				tag, more := dollarTag(buf[s.i:], atEOF)
+				if more {
+					return 0, 0, 0, false
+				}
+				if tag != "" {
+					s.state = splitDollarQuote
+					s.tag = tag
+					s.i += len(tag) - 1
+				}
+			}
+			if s.state != splitLineComment && s.state != splitBlockComment && !isSpace(c) && !s.content {
+				s.content = true
+				s.code = at
+			}
+		case splitSingleQuote, splitDoubleQuote, splitBacktick, splitBracket:
+			quote := closingQuote(s.state)
+			if c == '\\' && s.state == splitSingleQuote && s.escapes {
+				s.i++
+			} else if c == quote {
+				next, more := peekByte(buf, s.i+1, atEOF)
+				if more {
+					return 0, 0, 0, false
+				}
+				if next == quote {
+					s.i++
+				} else {
+					s.state = splitCode
+				}
+			}
+		case splitLineComment:
+			if c == '\n' {
+				s.state = splitCode
+			}
+		case splitBlockComment:
+			if c == '*' {
+				next, more := peekByte(buf, s.i+1, atEOF)
+				if more {
+					return 0, 0, 0, false
+				}
+				if next == '/' {
+					s.state = splitCode
+					s.i++
+				}
+			}
+		case splitDollarQuote:
+			if matched, more := hasPrefix(buf, s.i, s.tag, atEOF); more {
+				return 0, 0, 0, false
+			} else if matched {
+				s.state = splitCode
+				s.i += len(s.tag) - 1
+			}
+		}
+		s.i++
+	}
+	if atEOF && s.content {
+		return s.found(buf, len(buf), len(buf))
+	}
+	if atEOF {
+		s.reset()
+	}
+	return 0, 0, len(buf), false
+}
+
+// found returns the statement ending at end, trimmed of trailing whitespace, and resets the splitter for the next one.
+func (s *splitter) found(buf string, end, consumed int) (int, int, int, bool) {
+	start := s.start
+	for end > start && isSpace(buf[end-1]) {
+		end--
+	}
+	s.reset()
+	return start, end, consumed, true
+}
+
+func (s *splitter) reset() {
+	s.state = splitCode
+	s.i = 0
+	s.start = -1
+	s.code = -1
+	s.content = false
+	s.depth = 0
+	s.values = -1
+	s.rowEnd = -1
+}
+
+func closingQuote(state splitState) byte {
+	switch state {
+	case splitDoubleQuote:
+		return '"'
+	case splitBacktick:
+		return '`'
+	case splitBracket:
+		return ']'
+	}
+	return '\''
+}
+
+// hasPrefix reports whether buf has prefix at i, or whether more of buf is needed to tell.
+func hasPrefix(buf string, i int, prefix string, atEOF bool) (matched bool, more bool) {
+	rest := buf[i:]
+	if len(rest) < len(prefix) {
+		return false, !atEOF && strings.HasPrefix(prefix, rest)
+	}
+	return rest[:len(prefix)] == prefix, false
+}
+
+// peekByte returns buf[i], 0 if buf ends before i, or whether more of buf is needed to tell.
+func peekByte(buf string, i int, atEOF bool) (byte, bool) {
+	if i < len(buf) {
+		return buf[i], false
+	}
+	return 0, !atEOF
+}
+
+// dollarTag returns the tag opening a Postgres dollar-quoted string at the start of s, e.g. "$$" or "$body$", if any.
+func dollarTag(s string, atEOF bool) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1], false
+		}
+		if !isIdentifierChar(s[i]) || (i == 1 && s[i] >= '0' && s[i] <= '9') {
+			// e.g. the placeholder "$1"
+			return "", false
+		}
+	}
+	return "", !atEOF
+}
+
+// delimiterCommand parses the MySQL client's "DELIMITER //" command at the start of s, returning the new delimiter
+// and the length of the command's line, or whether more of s is needed to tell.
+func delimiterCommand(s string, atEOF bool) (string, int, bool) {
+	const command = "DELIMITER"
+	if len(s) <= len(command) {
+		return "", 0, !atEOF && strings.HasPrefix(command, strings.ToUpper(s))
+	}
+	if strings.ToUpper(s[:len(command)]) != command || (s[len(command)] != ' ' && s[len(command)] != '\t') {
+		return "", 0, false
+	}
+	lineEnd := strings.IndexByte(s, '\n')
+	if lineEnd < 0 {
+		if !atEOF {
+			return "", 0, true
+		}
+		lineEnd = len(s)
+	}
+	fields := strings.Fields(s[len(command):lineEnd])
+	if len(fields) == 0 {
+		return "", 0, false
+	}
+	return fields[0], lineEnd, false
+}
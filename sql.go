@@ -2,7 +2,6 @@ package sqlparser
 
 import (
 	"fmt"
-	"log"
 	"regexp"
 	"strconv"
 	"strings"
@@ -11,18 +10,61 @@ import (
 )
 
 // Parse takes a string representing a SQL query and parses it into a query.Query struct. It may fail.
+// Comments are skipped, but kept in the query's Comments, or Hints for MySQL optimizer hints like "/*+ NO_ICP(t) */".
 func Parse(sqls string) (query.Query, error) {
+	return ParseOpts(sqls, ParseOptions{})
+}
+
+// Dialect selects the lexical rules of a SQL dialect, where they differ. Parsed queries are written back in it.
+type Dialect = query.Dialect
+
+const (
+	// ANSI is standard SQL, as spoken by Postgres and SQL Server: backslashes in strings are plain characters, except
+	// in E'...' escape strings
+	ANSI = query.ANSI
+	// MySQL treats backslashes in strings as escapes, as in 'it\'s'
+	MySQL = query.MySQL
+)
+
+// ParseOptions configures ParseOpts.
+type ParseOptions struct {
+	Dialect Dialect
+	// Recover makes parsing carry on after a syntax error instead of stopping at it, resuming at the next clause
+	// (FROM, WHERE, GROUP BY or ORDER BY) or the next item of a list (after a comma or closing parens). Every error
+	// found is returned, as ParseErrors, along with what could be parsed of the query.
+	Recover bool
+}
 
-	sqls = strings.Replace(sqls, "`", "", -1)
+// ParseError is a syntax error found with ParseOptions.Recover, at the position of the token it was found at.
+type ParseError struct {
+	Err      error
+	Position query.Position
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("at line %v, column %v: %v", e.Position.Line, e.Position.Column, e.Err)
+}
 
-	space := regexp.MustCompile(`\s+`)
-	sqls = space.ReplaceAllString(sqls, " ")
+// ParseErrors are the syntax errors found in a query with ParseOptions.Recover, in the order they were found.
+type ParseErrors []ParseError
 
-	qs, err := ParseMany([]string{sqls})
-	if len(qs) == 0 {
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ParseOpts is like Parse, with options. Without Recover, it stops at the first error and returns an empty query.
+func ParseOpts(sqls string, opts ParseOptions) (query.Query, error) {
+	p := newParser(sqls, 0, opts.Dialect)
+	p.recover = opts.Recover
+	q, err := p.parse()
+	if err != nil && !opts.Recover {
 		return query.Query{}, err
 	}
-	return qs[0], err
+	return q, err
 }
 
 // ParseMany takes a string slice representing many SQL queries and parses them into a query.Query struct slice.
@@ -30,13 +72,8 @@ func Parse(sqls string) (query.Query, error) {
 func ParseMany(sqls []string) ([]query.Query, error) {
 	qs := []query.Query{}
 
-	space := regexp.MustCompile(`\s+`)
-
 	for _, sql := range sqls {
-		sql = strings.Replace(sql, "`", "", -1)
-		sql = space.ReplaceAllString(sql, " ")
-
-		q, err := parse(sql)
+		q, err := parse(sql, ANSI)
 		if err != nil {
 			return qs, err
 		}
@@ -45,8 +82,16 @@ func ParseMany(sqls []string) ([]query.Query, error) {
 	return qs, nil
 }
 
-func parse(sql string) (query.Query, error) {
-	return (&parser{0, strings.TrimSpace(sql), stepType, query.Query{}, nil, ""}).parse()
+func parse(sql string, dialect Dialect) (query.Query, error) {
+	return newParser(sql, 0, dialect).parse()
+}
+
+// newParser makes a parser for the query starting at offset i of sql.
+func newParser(sql string, i int, dialect Dialect) *parser {
+	p := &parser{i: i, sql: sql, dialect: dialect, step: stepType, lastComment: -1}
+	p.query.Dialect = dialect
+	p.popWhitespace()
+	return p
 }
 
 type step int
@@ -67,13 +112,19 @@ const (
 	stepInsertValues
 	stepInsertValuesCommaOrClosingParens
 	stepInsertValuesCommaBeforeOpeningParens
+	stepInsertOnDuplicateField
+	stepInsertOnDuplicateValue
+	stepInsertOnDuplicateComma
 	stepUpdateTable
 	stepUpdateSet
 	stepUpdateField
 	stepUpdateEquals
 	stepUpdateValue
 	stepUpdateComma
+	stepUpdateFrom
+	stepDeleteTargets
 	stepDeleteFromTable
+	stepDeleteUsing
 	stepWhere
 	stepWhereField
 	stepWhereOperator
@@ -82,30 +133,171 @@ const (
 	stepOrder
 	stepOrderField
 	stepOrderDirectionOrComma
+	stepLimit
 	stepJoin
 	stepJoinTable
 	stepJoinCondition
+	stepReturning
+	stepReturningField
+	stepReturningComma
+	stepTransaction
+	stepSavepointName
+	stepSetField
+	stepSetValue
+	stepUseDatabase
+	stepExplain
+	stepShow
+	stepDescribe
+	stepPrivileges
+	stepPrivilegeObjects
+	stepGrantees
 )
 
 type parser struct {
-	i               int
-	sql             string
-	step            step
-	query           query.Query
-	err             error
-	nextUpdateField string
+	i           int
+	sql         string
+	step        step
+	query       query.Query
+	dialect     Dialect
+	err         error
+	updateTuple bool
+	lastComment int         // start of the last comment seen, so comments aren't recorded twice when backtracking
+	lastEnd     int         // end of the last token popped, before the whitespace after it
+	recover     bool        // whether to carry on after errors, as with ParseOptions.Recover
+	errors      ParseErrors // the errors recovered from
+	// line is the line lineOffset is on, which starts at lineStart, so positions needn't count lines from the start
+	// of the query
+	line       int
+	lineStart  int
+	lineOffset int
 }
 
 func (p *parser) parse() (query.Query, error) {
 	q, err := p.doParse()
+	// nothing can be recovered of a query whose type is unknown
+	for p.recover && err != nil && p.step != stepType {
+		p.addError(err)
+		p.err = nil
+		p.resynchronise()
+		q, err = p.doParse()
+	}
 	p.err = err
 	if p.err == nil {
 		p.err = p.validate()
 	}
 	//p.logError()
+	if p.recover {
+		if p.err != nil && !p.errors.has(p.err) {
+			p.addError(p.err)
+		}
+		if len(p.errors) > 0 {
+			return q, p.errors
+		}
+	}
 	return q, p.err
 }
 
+// addError records an error found at the current position, to carry on parsing after it.
+func (p *parser) addError(err error) {
+	p.errors = append(p.errors, ParseError{Err: err, Position: p.tokenPosition()})
+}
+
+// has reports whether an error with the same message was found already, e.g. by validation after recovering.
+func (e ParseErrors) has(err error) bool {
+	for _, found := range e {
+		if found.Err.Error() == err.Error() {
+			return true
+		}
+	}
+	return false
+}
+
+// resynchronise skips tokens after an error up to the next clause keyword, or the next comma or closing parens of
+// the list the error was in, and sets the step to resume parsing there. A GROUP BY clause, which isn't parsed, is
+// skipped too.
+func (p *parser) resynchronise() {
+	failed, start := p.step, p.i
+	grouping := false
+	for p.i < len(p.sql) {
+		if p.reservedWordLength("GROUP BY") > 0 {
+			grouping = true
+		} else if next, pop, ok := p.resumeStep(failed, grouping); ok && (pop || p.i > start || next != failed) {
+			if pop {
+				p.pop()
+			}
+			p.step = next
+			return
+		}
+		p.skipToken()
+	}
+}
+
+// skipToken pops the next token, or the next byte if it doesn't start a token, e.g. a stray ";".
+func (p *parser) skipToken() {
+	if _, ln := p.peekWithLength(); ln > 0 {
+		p.pop()
+		return
+	}
+	p.i++
+	p.lastEnd = p.i
+	p.popWhitespace()
+}
+
+// resumeStep returns the step to resume parsing at the next token after an error in step failed, if any, and whether
+// the token has to be popped first. Commas and closing parens are only resumed at in the list the error was in.
+func (p *parser) resumeStep(failed step, grouping bool) (step, bool, bool) {
+	token := strings.ToUpper(p.peek())
+	switch p.query.Type {
+	case query.Select, query.Update, query.Delete:
+		switch {
+		case token == "WHERE":
+			return stepWhere, false, true
+		case token == "ORDER BY":
+			return stepOrder, false, true
+		case token == "FROM" && p.query.Type == query.Select:
+			return stepSelectFrom, false, true
+		case token == "FROM" && p.query.Type == query.Update:
+			return stepUpdateFrom, false, true
+		case token == "FROM" && p.query.Type == query.Delete:
+			return stepDeleteFromTable, true, true
+		}
+	}
+	if grouping {
+		return failed, false, false
+	}
+	switch token {
+	case ",":
+		switch failed {
+		case stepSelectField, stepSelectComma:
+			return stepSelectField, true, true
+		case stepInsertFields, stepInsertFieldsCommaOrClosingParens:
+			return stepInsertFields, true, true
+		case stepInsertValues, stepInsertValuesCommaOrClosingParens:
+			return stepInsertValues, true, true
+		case stepInsertValuesOpeningParens, stepInsertValuesCommaBeforeOpeningParens:
+			return stepInsertValuesOpeningParens, true, true
+		case stepUpdateField, stepUpdateEquals, stepUpdateValue, stepUpdateComma:
+			return stepUpdateField, true, true
+		case stepInsertOnDuplicateField, stepInsertOnDuplicateValue, stepInsertOnDuplicateComma:
+			return stepInsertOnDuplicateField, true, true
+		case stepOrderField, stepOrderDirectionOrComma:
+			return stepOrderField, true, true
+		case stepReturningField, stepReturningComma:
+			return stepReturningField, true, true
+		case stepSetField, stepSetValue:
+			return stepSetField, true, true
+		}
+	case ")":
+		switch failed {
+		case stepInsertFields, stepInsertFieldsCommaOrClosingParens:
+			return stepInsertValuesRWord, true, true
+		case stepInsertValues, stepInsertValuesCommaOrClosingParens:
+			return stepInsertValuesCommaBeforeOpeningParens, true, true
+		}
+	}
+	return failed, false, false
+}
+
 func (p *parser) doParse() (query.Query, error) {
 	for {
 		if p.i >= len(p.sql) {
@@ -129,21 +321,94 @@ func (p *parser) doParse() (query.Query, error) {
 				p.step = stepInsertTable
 			case "UPDATE":
 				p.query.Type = query.Update
-				p.query.Updates = map[string]string{}
 				p.pop()
 				p.step = stepUpdateTable
 			case "DELETE FROM":
 				p.query.Type = query.Delete
 				p.pop()
 				p.step = stepDeleteFromTable
+			case "DELETE":
+				p.query.Type = query.Delete
+				p.pop()
+				p.step = stepDeleteTargets
+			case "BEGIN":
+				p.query.Type = query.Begin
+				p.pop()
+				p.step = stepTransaction
+			case "START":
+				p.query.Type = query.Begin
+				p.pop()
+				if strings.ToUpper(p.peek()) != "TRANSACTION" {
+					return p.query, fmt.Errorf("at START: expected TRANSACTION")
+				}
+				p.step = stepTransaction
+			case "COMMIT":
+				p.query.Type = query.Commit
+				p.pop()
+				p.step = stepTransaction
+			case "ROLLBACK":
+				p.query.Type = query.Rollback
+				p.pop()
+				p.step = stepTransaction
+			case "SAVEPOINT":
+				p.query.Type = query.Savepoint
+				p.pop()
+				p.step = stepSavepointName
+			case "RELEASE":
+				p.query.Type = query.Release
+				p.pop()
+				if strings.ToUpper(p.peek()) == "SAVEPOINT" {
+					p.pop()
+				}
+				p.step = stepSavepointName
+			case "SET":
+				p.query.Type = query.Set
+				p.pop()
+				switch scope := strings.ToUpper(p.peek()); scope {
+				case "SESSION", "LOCAL", "GLOBAL":
+					p.query.Scope = scope
+					p.pop()
+				}
+				p.step = stepSetField
+			case "USE":
+				p.query.Type = query.Use
+				p.pop()
+				p.step = stepUseDatabase
+			case "EXPLAIN":
+				p.query.Type = query.Explain
+				p.pop()
+				p.step = stepExplain
+			case "SHOW":
+				p.query.Type = query.Show
+				p.pop()
+				p.step = stepShow
+			case "DESCRIBE", "DESC":
+				p.query.Type = query.Describe
+				p.pop()
+				p.step = stepDescribe
+			case "GRANT":
+				p.query.Type = query.Grant
+				p.pop()
+				p.step = stepPrivileges
+			case "REVOKE":
+				p.query.Type = query.Revoke
+				p.pop()
+				if strings.ToUpper(p.peek()) == "GRANT" {
+					p.pop()
+					if strings.ToUpper(p.pop()) != "OPTION" || strings.ToUpper(p.pop()) != "FOR" {
+						return p.query, fmt.Errorf("at REVOKE: expected GRANT OPTION FOR")
+					}
+					p.query.GrantOption = true
+				}
+				p.step = stepPrivileges
 			default:
 				return p.query, fmt.Errorf("invalid query type")
 			}
 		case stepTop:
 			p.pop()
 			m, err := strconv.Atoi(p.pop())
-			if err != nil {
-				log.Fatal("Unable to convert integer in TOP expression")
+			if err != nil || m < 0 {
+				return p.query, fmt.Errorf("at TOP: expected number of rows")
 			}
 			p.query.MaxRows = m
 			p.step = stepSelectField
@@ -152,7 +417,8 @@ func (p *parser) doParse() (query.Query, error) {
 			if !isIdentifierOrAsterisk(identifier) {
 				return p.query, fmt.Errorf("at SELECT: expected field to SELECT")
 			}
-			p.query.Fields = append(p.query.Fields, identifier)
+			p.query.Fields = append(p.query.Fields, p.identifier(identifier))
+			p.query.FieldPositions = append(p.query.FieldPositions, p.tokenPosition())
 			p.pop()
 			maybeFrom := p.peek()
 			if strings.ToUpper(maybeFrom) == "AS" {
@@ -164,7 +430,7 @@ func (p *parser) doParse() (query.Query, error) {
 				if p.query.Aliases == nil {
 					p.query.Aliases = make(map[string]string)
 				}
-				p.query.Aliases[identifier] = alias
+				p.query.Aliases[p.identifier(identifier)] = p.identifier(alias)
 				p.pop()
 				maybeFrom = p.peek()
 			}
@@ -193,13 +459,7 @@ func (p *parser) doParse() (query.Query, error) {
 				return p.query, fmt.Errorf("at SELECT: expected quoted table name")
 			}
 
-			if strings.Contains(tableName, ".") {
-				parts := strings.Split(tableName, ".")
-				p.query.Database = parts[0]
-				tableName = parts[1]
-			}
-
-			p.query.TableName = tableName
+			p.setTableName(tableName)
 			p.pop()
 			look := p.peek()
 			if strings.ToUpper(look) == "WHERE" {
@@ -208,6 +468,8 @@ func (p *parser) doParse() (query.Query, error) {
 				p.step = stepOrder
 			} else if strings.Contains(strings.ToUpper(look), "JOIN") {
 				p.step = stepJoin
+			} else if strings.ToUpper(look) == "LIMIT" {
+				p.step = stepLimit
 			}
 		case stepInsertTable:
 			tableName := p.peek()
@@ -215,13 +477,7 @@ func (p *parser) doParse() (query.Query, error) {
 				return p.query, fmt.Errorf("at INSERT INTO: expected quoted table name")
 			}
 
-			if strings.Contains(tableName, ".") {
-				parts := strings.Split(tableName, ".")
-				p.query.Database = parts[0]
-				tableName = parts[1]
-			}
-
-			p.query.TableName = tableName
+			p.setTableName(tableName)
 			p.pop()
 			p.step = stepInsertFieldsOpeningParens
 		case stepDeleteFromTable:
@@ -230,14 +486,60 @@ func (p *parser) doParse() (query.Query, error) {
 				return p.query, fmt.Errorf("at DELETE FROM: expected quoted table name")
 			}
 
-			if strings.Contains(tableName, ".") {
-				parts := strings.Split(tableName, ".")
-				p.query.Database = parts[0]
-				tableName = parts[1]
+			p.setTableName(tableName)
+			p.pop()
+			look := strings.ToUpper(p.peek())
+			if look == "OUTPUT" {
+				p.step = stepReturning
+				continue
+			}
+			if look == "USING" {
+				p.step = stepDeleteUsing
+				continue
+			}
+			if strings.Contains(look, "JOIN") {
+				p.step = stepJoin
+				continue
+			}
+			p.step = stepWhere
+		case stepDeleteTargets:
+			for {
+				tableName := p.peek()
+				if !isIdentifier(tableName) {
+					return p.query, fmt.Errorf("at DELETE: expected table name to delete from")
+				}
+				p.query.Targets = append(p.query.Targets, p.identifier(tableName))
+				p.query.TargetPositions = append(p.query.TargetPositions, p.tokenPosition())
+				p.pop()
+				if p.peek() != "," {
+					break
+				}
+				p.pop()
+			}
+			if strings.ToUpper(p.peek()) != "FROM" {
+				return p.query, fmt.Errorf("at DELETE: expected FROM")
 			}
-
-			p.query.TableName = tableName
 			p.pop()
+			p.step = stepDeleteFromTable
+		case stepDeleteUsing:
+			p.pop()
+			for {
+				tableName := p.peek()
+				if !isIdentifier(tableName) {
+					return p.query, fmt.Errorf("at DELETE FROM: expected table name after USING")
+				}
+				p.query.Using = append(p.query.Using, p.identifier(tableName))
+				p.query.UsingPositions = append(p.query.UsingPositions, p.tokenPosition())
+				p.pop()
+				if p.peek() != "," {
+					break
+				}
+				p.pop()
+			}
+			if strings.Contains(strings.ToUpper(p.peek()), "JOIN") {
+				p.step = stepJoin
+				continue
+			}
 			p.step = stepWhere
 		case stepUpdateTable:
 
@@ -247,32 +549,47 @@ func (p *parser) doParse() (query.Query, error) {
 				return p.query, fmt.Errorf("at UPDATE: expected quoted table name")
 			}
 
-			if strings.Contains(tableName, ".") {
-				parts := strings.Split(tableName, ".")
-				p.query.Database = parts[0]
-				tableName = parts[1]
-			}
-
-			p.query.TableName = tableName
+			p.setTableName(tableName)
 			p.pop()
+			if strings.Contains(strings.ToUpper(p.peek()), "JOIN") {
+				p.step = stepJoin
+				continue
+			}
 			p.step = stepUpdateSet
 		case stepUpdateSet:
 			setRWord := p.peek()
-			if setRWord != "SET" {
+			if strings.ToUpper(setRWord) != "SET" {
 				return p.query, fmt.Errorf("at UPDATE: expected 'SET'")
 			}
 			p.pop()
 			p.step = stepUpdateField
 		case stepUpdateField:
-			identifier := p.peek()
-
-			if !isIdentifier(identifier) && isReservedWord(identifier) {
-				//this case handles when a reserved word is used in the query
-				return p.query, fmt.Errorf("at UPDATE: expected at least one field to update")
-				//log.Println("Identifier Found")
+			assignment := query.Assignment{Position: p.tokenPosition()}
+			p.updateTuple = p.peek() == "("
+			if p.updateTuple {
+				p.pop()
 			}
-			p.nextUpdateField = identifier
-			p.pop()
+			for {
+				identifier := p.peek()
+				if !isIdentifier(identifier) {
+					return p.query, fmt.Errorf("at UPDATE: expected at least one field to update")
+				}
+				assignment.Fields = append(assignment.Fields, p.identifier(identifier))
+				assignment.FieldPositions = append(assignment.FieldPositions, p.tokenPosition())
+				p.pop()
+				if !p.updateTuple {
+					break
+				}
+				commaOrClosingParens := p.peek()
+				if commaOrClosingParens != "," && commaOrClosingParens != ")" {
+					return p.query, fmt.Errorf("at UPDATE: expected comma or closing parens")
+				}
+				p.pop()
+				if commaOrClosingParens == ")" {
+					break
+				}
+			}
+			p.query.Updates = append(p.query.Updates, assignment)
 			p.step = stepUpdateEquals
 		case stepUpdateEquals:
 			equalsRWord := p.peek()
@@ -282,21 +599,49 @@ func (p *parser) doParse() (query.Query, error) {
 			p.pop()
 			p.step = stepUpdateValue
 		case stepUpdateValue:
-			quotedValue, ln := p.peekQuotedStringWithLength()
-			if ln == 0 {
-				quotedValue, ln = p.peekWithLength()
-				if ln == 0 {
-					return p.query, fmt.Errorf("at UPDATE: expected quoted value")
+			currentAssignment := p.query.Updates[len(p.query.Updates)-1]
+			if p.updateTuple {
+				if p.peek() != "(" {
+					return p.query, fmt.Errorf("at UPDATE: expected opening parens")
 				}
+				p.pop()
 			}
-			p.query.Updates[p.nextUpdateField] = quotedValue
-			p.nextUpdateField = ""
-			p.pop()
-			maybeWhere := p.peek()
-			if strings.ToUpper(maybeWhere) == "WHERE" {
+			for {
+				value, err := p.parseExpr()
+				if err != nil {
+					return p.query, fmt.Errorf("at UPDATE: %v", err)
+				}
+				currentAssignment.Values = append(currentAssignment.Values, value)
+				if !p.updateTuple {
+					break
+				}
+				commaOrClosingParens := p.peek()
+				if commaOrClosingParens != "," && commaOrClosingParens != ")" {
+					return p.query, fmt.Errorf("at UPDATE: expected comma or closing parens")
+				}
+				p.pop()
+				if commaOrClosingParens == ")" {
+					break
+				}
+			}
+			if len(currentAssignment.Values) != len(currentAssignment.Fields) {
+				return p.query, fmt.Errorf("at UPDATE: value count doesn't match field count")
+			}
+			currentAssignment.Position.End = p.lastEnd
+			p.query.Updates[len(p.query.Updates)-1] = currentAssignment
+			maybeWhere := strings.ToUpper(p.peek())
+			if maybeWhere == "WHERE" {
 				p.step = stepWhere
 				continue
 			}
+			if maybeWhere == "FROM" {
+				p.step = stepUpdateFrom
+				continue
+			}
+			if maybeWhere == "OUTPUT" || maybeWhere == "RETURNING" {
+				p.step = stepReturning
+				continue
+			}
 			p.step = stepUpdateComma
 		case stepUpdateComma:
 			commaRWord := p.peek()
@@ -305,6 +650,35 @@ func (p *parser) doParse() (query.Query, error) {
 			}
 			p.pop()
 			p.step = stepUpdateField
+		case stepUpdateFrom:
+			p.pop()
+			for {
+				tableName := p.peek()
+				if !isIdentifier(tableName) {
+					return p.query, fmt.Errorf("at UPDATE: expected table name after FROM")
+				}
+				p.query.From = append(p.query.From, p.identifier(tableName))
+				p.query.FromPositions = append(p.query.FromPositions, p.tokenPosition())
+				p.pop()
+				if p.peek() != "," {
+					break
+				}
+				p.pop()
+			}
+			maybeWhere := strings.ToUpper(p.peek())
+			if (maybeWhere == "OUTPUT" && !p.query.Output) || maybeWhere == "RETURNING" {
+				p.step = stepReturning
+				continue
+			}
+			if strings.Contains(maybeWhere, "JOIN") {
+				// the JOINs of a T-SQL FROM, which can't have JOINs before SET as well
+				if len(p.query.Joins) > 0 {
+					return p.query, fmt.Errorf("at UPDATE: expected JOINs either before SET or after FROM")
+				}
+				p.step = stepJoin
+				continue
+			}
+			p.step = stepWhere
 		case stepWhere:
 			whereRWord := p.peek()
 			if strings.ToUpper(whereRWord) != "WHERE" {
@@ -317,7 +691,8 @@ func (p *parser) doParse() (query.Query, error) {
 			if !isIdentifier(identifier) {
 				return p.query, fmt.Errorf("at WHERE: expected field")
 			}
-			p.query.Conditions = append(p.query.Conditions, query.Condition{Operand1: identifier, Operand1IsField: true})
+			position := p.tokenPosition()
+			p.query.Conditions = append(p.query.Conditions, query.Condition{Operand1: p.identifier(identifier), Operand1IsField: true, Position: position, Operand1Position: position})
 			p.pop()
 			p.step = stepWhereOperator
 		case stepWhereOperator:
@@ -343,22 +718,35 @@ func (p *parser) doParse() (query.Query, error) {
 			p.pop()
 			p.step = stepWhereValue
 		case stepWhereValue:
-			quotedValue, ln := p.peekQuotedStringWithLength()
-			if ln == 0 {
-				quotedValue, ln = p.peekWithLength()
-				if ln == 0 {
-					return p.query, fmt.Errorf("at WHERE: expected quoted value")
+			currentCondition := p.query.Conditions[len(p.query.Conditions)-1]
+			start := p.position(p.i, p.i)
+			value, err := p.parseExpr()
+			if err != nil {
+				return p.query, fmt.Errorf("at WHERE: %v", err)
+			}
+			// literals and fields, e.g. "b.id" in "a.id = b.id", are held as they are, anything else as an expression
+			switch v := value.(type) {
+			case query.Literal:
+				currentCondition.Operand2, currentCondition.Operand2Kind, currentCondition.Operand2Raw = v.Value, v.Kind, v.Raw
+			case query.Column:
+				currentCondition.Operand2, currentCondition.Operand2IsField = v.Name, true
+				if v.Table != "" {
+					currentCondition.Operand2 = v.Table + "." + v.Name
 				}
+			default:
+				currentCondition.Operand2Expr = value
 			}
-			currentCondition := p.query.Conditions[len(p.query.Conditions)-1]
-			currentCondition.Operand2 = quotedValue
-			currentCondition.Operand2IsField = false
+			currentCondition.Operand2Position = p.spanFrom(start)
+			currentCondition.Position.End = p.lastEnd
 			p.query.Conditions[len(p.query.Conditions)-1] = currentCondition
-			p.pop()
 			oWord := p.peek()
 			if strings.ToUpper(oWord) == "ORDER BY" {
 				p.pop()
 				p.step = stepOrderField
+			} else if strings.ToUpper(oWord) == "LIMIT" {
+				p.step = stepLimit
+			} else if strings.ToUpper(oWord) == "RETURNING" && p.query.Type != query.Select {
+				p.step = stepReturning
 			} else {
 				p.step = stepWhereAnd
 			}
@@ -381,8 +769,9 @@ func (p *parser) doParse() (query.Query, error) {
 			if !isIdentifier(identifier) {
 				return p.query, fmt.Errorf("at ORDER BY: expected field to ORDER")
 			}
-			p.query.OrderFields = append(p.query.OrderFields, identifier)
+			p.query.OrderFields = append(p.query.OrderFields, p.identifier(identifier))
 			p.query.OrderDir = append(p.query.OrderDir, "ASC")
+			p.query.OrderFieldPositions = append(p.query.OrderFieldPositions, p.tokenPosition())
 			p.pop()
 			p.step = stepOrderDirectionOrComma
 		case stepOrderDirectionOrComma:
@@ -393,32 +782,55 @@ func (p *parser) doParse() (query.Query, error) {
 				p.pop()
 				p.query.OrderDir[len(p.query.OrderDir)-1] = commaRWord
 				continue
+			} else if commaRWord == "LIMIT" {
+				p.step = stepLimit
+				continue
 			}
 			p.step = stepOrderField
+		case stepLimit:
+			p.pop()
+			m, err := strconv.Atoi(p.peek())
+			if err != nil || m < 0 {
+				return p.query, fmt.Errorf("at LIMIT: expected number of rows")
+			}
+			p.query.MaxRows, p.query.Limit = m, true
+			p.pop()
+			if next := strings.ToUpper(p.peek()); next == "OFFSET" || next == "," {
+				// a query has no offset, for "LIMIT 10 OFFSET 5" or MySQL's "LIMIT 5, 10"
+				return p.query, fmt.Errorf("at LIMIT: OFFSET is not supported")
+			}
+			if p.i < len(p.sql) {
+				return p.query, fmt.Errorf("at LIMIT: expected end of query")
+			}
 		case stepJoin:
 			joinType := p.peek()
-			p.query.Joins = append(p.query.Joins, query.Join{Type: joinType, Table: "UNKNOWN"})
+			p.query.Joins = append(p.query.Joins, query.Join{Type: joinType, Table: "UNKNOWN", Position: p.tokenPosition()})
 			p.pop()
 			p.step = stepJoinTable
 		case stepJoinTable:
 			joinTable := p.peek()
 			currentJoin := p.query.Joins[len(p.query.Joins)-1]
-			currentJoin.Table = joinTable
+			currentJoin.Table = p.identifier(joinTable)
+			currentJoin.TablePosition = p.tokenPosition()
+			currentJoin.Position.End = currentJoin.TablePosition.End
 			p.query.Joins[len(p.query.Joins)-1] = currentJoin
 			p.pop()
 			if strings.ToUpper(p.peek()) == "ON" {
 				p.step = stepJoinCondition
+			} else if strings.ToUpper(p.peek()) == "LIMIT" {
+				p.step = stepLimit
 			} else {
 				p.step = stepOrder
 			}
 		case stepJoinCondition:
 			p.pop()
+			op1Position := p.tokenPosition()
 			op1 := p.pop()
-			op1split := strings.Split(op1, ".")
+			op1split := p.identifierParts(op1)
 			if len(op1split) != 2 {
 				return p.query, fmt.Errorf("at ON: expected <tablename>.<fieldname>")
 			}
-			currentCondition := query.JoinCondition{Table1: op1split[0], Operand1: op1split[1]}
+			currentCondition := query.JoinCondition{Table1: op1split[0], Operand1: op1split[1], Position: op1Position, Operand1Position: op1Position}
 			operator := p.peek()
 			switch operator {
 			case "=":
@@ -437,28 +849,45 @@ func (p *parser) doParse() (query.Query, error) {
 				return p.query, fmt.Errorf("at ON: unknown operator")
 			}
 			p.pop()
+			op2Position := p.tokenPosition()
 			op2 := p.pop()
-			op2split := strings.Split(op2, ".")
+			op2split := p.identifierParts(op2)
 			if len(op2split) != 2 {
 				return p.query, fmt.Errorf("at ON: expected <tablename>.<fieldname>")
 			}
 			currentCondition.Table2 = op2split[0]
 			currentCondition.Operand2 = op2split[1]
+			currentCondition.Operand2Position = op2Position
+			currentCondition.Position.End = op2Position.End
 			currentJoin := p.query.Joins[len(p.query.Joins)-1]
 			currentJoin.Conditions = append(currentJoin.Conditions, currentCondition)
+			currentJoin.Position.End = op2Position.End
 			p.query.Joins[len(p.query.Joins)-1] = currentJoin
 			nextOp := p.peek()
 			if strings.ToUpper(nextOp) == "WHERE" {
 				p.step = stepWhere
 			} else if strings.ToUpper(nextOp) == "ORDER BY" {
 				p.step = stepOrder
+			} else if strings.ToUpper(nextOp) == "LIMIT" {
+				p.step = stepLimit
 			} else if strings.ToUpper(nextOp) == "AND" {
 				p.step = stepJoinCondition
 			} else if strings.Contains(strings.ToUpper(nextOp), "JOIN") {
 				p.step = stepJoin
+			} else if strings.ToUpper(nextOp) == "SET" && p.query.Type == query.Update {
+				p.step = stepUpdateSet
 			}
 		case stepInsertFieldsOpeningParens:
 			openingParens := p.peek()
+			// the field list is optional, as in "INSERT INTO t VALUES (1, 2)" or "INSERT INTO t OUTPUT inserted.id VALUES (1)"
+			switch strings.ToUpper(openingParens) {
+			case "VALUES":
+				p.step = stepInsertValuesRWord
+				continue
+			case "OUTPUT":
+				p.step = stepReturning
+				continue
+			}
 			if len(openingParens) != 1 || openingParens != "(" {
 				return p.query, fmt.Errorf("at INSERT INTO: expected opening parens")
 			}
@@ -466,10 +895,11 @@ func (p *parser) doParse() (query.Query, error) {
 			p.step = stepInsertFields
 		case stepInsertFields:
 			identifier := p.peek()
-			if !isIdentifier(identifier) && isReservedWord(identifier) {
+			if !isIdentifier(identifier) {
 				return p.query, fmt.Errorf("at INSERT INTO: expected at least one field to insert")
 			}
-			p.query.Fields = append(p.query.Fields, identifier)
+			p.query.Fields = append(p.query.Fields, p.identifier(identifier))
+			p.query.FieldPositions = append(p.query.FieldPositions, p.tokenPosition())
 			p.pop()
 			p.step = stepInsertFieldsCommaOrClosingParens
 		case stepInsertFieldsCommaOrClosingParens:
@@ -482,6 +912,10 @@ func (p *parser) doParse() (query.Query, error) {
 				p.step = stepInsertFields
 				continue
 			}
+			if strings.ToUpper(p.peek()) == "OUTPUT" {
+				p.step = stepReturning
+				continue
+			}
 			p.step = stepInsertValuesRWord
 		case stepInsertValuesRWord:
 			valuesRWord := p.peek()
@@ -495,19 +929,15 @@ func (p *parser) doParse() (query.Query, error) {
 			if openingParens != "(" {
 				return p.query, fmt.Errorf("at INSERT INTO: expected opening parens")
 			}
-			p.query.Inserts = append(p.query.Inserts, []string{})
+			p.query.Inserts = append(p.query.Inserts, []query.Expr{})
 			p.pop()
 			p.step = stepInsertValues
 		case stepInsertValues:
-			quotedValue, ln := p.peekQuotedStringWithLength()
-			if ln == 0 {
-				quotedValue, ln = p.peekWithLength()
-				if ln == 0 {
-					return p.query, fmt.Errorf("at INSERT INTO: expected quoted value")
-				}
+			value, err := p.parseExpr()
+			if err != nil {
+				return p.query, fmt.Errorf("at INSERT INTO: %v", err)
 			}
-			p.query.Inserts[len(p.query.Inserts)-1] = append(p.query.Inserts[len(p.query.Inserts)-1], quotedValue)
-			p.pop()
+			p.query.Inserts[len(p.query.Inserts)-1] = append(p.query.Inserts[len(p.query.Inserts)-1], value)
 			p.step = stepInsertValuesCommaOrClosingParens
 		case stepInsertValuesCommaOrClosingParens:
 			commaOrClosingParens := p.peek()
@@ -525,24 +955,449 @@ func (p *parser) doParse() (query.Query, error) {
 			}
 			p.step = stepInsertValuesCommaBeforeOpeningParens
 		case stepInsertValuesCommaBeforeOpeningParens:
-			commaRWord := p.peek()
-			if strings.ToUpper(commaRWord) != "," && isReservedWord(commaRWord) {
+			commaRWord := strings.ToUpper(p.peek())
+			switch commaRWord {
+			case ",":
+				p.pop()
+				p.step = stepInsertValuesOpeningParens
+			case "RETURNING":
+				p.step = stepReturning
+			case "ON DUPLICATE KEY UPDATE":
+				p.pop()
+				p.step = stepInsertOnDuplicateField
+			default:
 				return p.query, fmt.Errorf("at INSERT INTO: expected comma")
 			}
+		case stepInsertOnDuplicateField:
+			identifier := p.peek()
+			if !isIdentifier(identifier) {
+				return p.query, fmt.Errorf("at ON DUPLICATE KEY UPDATE: expected field to update")
+			}
+			position := p.tokenPosition()
+			p.query.Updates = append(p.query.Updates, query.Assignment{Fields: []string{p.identifier(identifier)}, Position: position, FieldPositions: []query.Position{position}})
 			p.pop()
+			if p.peek() != "=" {
+				return p.query, fmt.Errorf("at ON DUPLICATE KEY UPDATE: expected '='")
+			}
+			p.pop()
+			p.step = stepInsertOnDuplicateValue
+		case stepInsertOnDuplicateValue:
+			value, err := p.parseExpr()
+			if err != nil {
+				return p.query, fmt.Errorf("at ON DUPLICATE KEY UPDATE: %v", err)
+			}
+			currentAssignment := p.query.Updates[len(p.query.Updates)-1]
+			currentAssignment.Values = []query.Expr{value}
+			currentAssignment.Position.End = p.lastEnd
+			p.query.Updates[len(p.query.Updates)-1] = currentAssignment
+			p.step = stepInsertOnDuplicateComma
+		case stepInsertOnDuplicateComma:
+			if p.peek() != "," {
+				return p.query, fmt.Errorf("at ON DUPLICATE KEY UPDATE: expected comma")
+			}
+			p.pop()
+			p.step = stepInsertOnDuplicateField
+		case stepTransaction:
+			switch strings.ToUpper(p.peek()) {
+			case "TRANSACTION", "WORK", "TRAN":
+				p.pop()
+			}
+			if p.query.Type == query.Rollback && strings.ToUpper(p.peek()) == "TO" {
+				p.pop()
+				if strings.ToUpper(p.peek()) == "SAVEPOINT" {
+					p.pop()
+				}
+				p.step = stepSavepointName
+				continue
+			}
+			if p.i < len(p.sql) {
+				return p.query, fmt.Errorf("at %v: expected end of query", strings.ToUpper(query.TypeString[p.query.Type]))
+			}
+		case stepSavepointName:
+			savepoint := p.peek()
+			if !isIdentifier(savepoint) {
+				return p.query, fmt.Errorf("at SAVEPOINT: expected savepoint name")
+			}
+			p.query.Savepoint = p.identifier(savepoint)
+			p.pop()
+			if p.i < len(p.sql) {
+				return p.query, fmt.Errorf("at SAVEPOINT: expected end of query")
+			}
+		case stepSetField:
+			identifier := p.peek()
+			if !isIdentifier(identifier) {
+				return p.query, fmt.Errorf("at SET: expected variable to set")
+			}
+			position := p.tokenPosition()
+			p.query.Updates = append(p.query.Updates, query.Assignment{Fields: []string{p.identifier(identifier)}, Position: position, FieldPositions: []query.Position{position}})
+			p.pop()
+			// "SET NAMES utf8" has neither "=" nor "TO"
+			if equalsRWord := strings.ToUpper(p.peek()); equalsRWord == "=" || equalsRWord == "TO" {
+				p.pop()
+			}
+			p.step = stepSetValue
+		case stepSetValue:
+			value, err := p.parseExpr()
+			if err != nil {
+				return p.query, fmt.Errorf("at SET: %v", err)
+			}
+			currentAssignment := p.query.Updates[len(p.query.Updates)-1]
+			currentAssignment.Values = append(currentAssignment.Values, value)
+			currentAssignment.Position.End = p.lastEnd
+			p.query.Updates[len(p.query.Updates)-1] = currentAssignment
+			if p.peek() != "," {
+				if p.i < len(p.sql) {
+					return p.query, fmt.Errorf("at SET: expected comma")
+				}
+				continue
+			}
+			p.pop()
+			// a comma either separates values, as in "SET search_path = a, b", or assignments, as in "SET a = 1, b = 2"
+			if p.isSetAssignment() {
+				p.step = stepSetField
+			}
+		case stepUseDatabase:
+			database := p.peek()
+			if !isIdentifier(database) {
+				return p.query, fmt.Errorf("at USE: expected database name")
+			}
+			p.query.Database = p.identifier(database)
+			p.pop()
+			if p.i < len(p.sql) {
+				return p.query, fmt.Errorf("at USE: expected end of query")
+			}
+		case stepExplain:
+			if strings.ToUpper(p.peek()) == "ANALYZE" {
+				p.query.Analyze = true
+				p.pop()
+			}
+			explainer := newParser(p.sql, p.i, p.dialect)
+			explainer.recover = p.recover
+			explained, err := explainer.parse()
+			if errs, ok := err.(ParseErrors); ok {
+				for _, e := range errs {
+					p.errors = append(p.errors, ParseError{Err: fmt.Errorf("at EXPLAIN: %v", e.Err), Position: e.Position})
+				}
+				err = nil
+			}
+			if err != nil {
+				return p.query, fmt.Errorf("at EXPLAIN: %v", err)
+			}
+			if !explained.Type.HasTable() {
+				return p.query, fmt.Errorf("at EXPLAIN: expected SELECT, INSERT, UPDATE or DELETE")
+			}
+			p.query.Explained = &explained
+			p.i = len(p.sql)
+		case stepShow:
+			what := strings.ToUpper(p.peek())
+			switch what {
+			case "TABLES", "DATABASES", "SCHEMAS":
+				p.query.Show = what
+				p.pop()
+			case "COLUMNS", "FIELDS", "INDEX", "INDEXES", "KEYS":
+				p.query.Show = what
+				p.pop()
+				if fromRWord := strings.ToUpper(p.peek()); fromRWord != "FROM" && fromRWord != "IN" {
+					return p.query, fmt.Errorf("at SHOW: expected FROM")
+				}
+				p.pop()
+				tableName := p.peek()
+				if !isIdentifier(tableName) {
+					return p.query, fmt.Errorf("at SHOW: expected table name")
+				}
+				p.setTableName(tableName)
+				p.pop()
+			case "CREATE":
+				p.pop()
+				if strings.ToUpper(p.peek()) != "TABLE" {
+					return p.query, fmt.Errorf("at SHOW: expected TABLE")
+				}
+				p.pop()
+				p.query.Show = "CREATE TABLE"
+				tableName := p.peek()
+				if !isIdentifier(tableName) {
+					return p.query, fmt.Errorf("at SHOW: expected table name")
+				}
+				p.setTableName(tableName)
+				p.pop()
+			default:
+				// e.g. Postgres' "SHOW search_path"
+				if !isIdentifier(what) {
+					return p.query, fmt.Errorf("at SHOW: expected what to show")
+				}
+				p.query.Show = p.pop()
+			}
+			if fromRWord := strings.ToUpper(p.peek()); p.query.Show != "CREATE TABLE" && (fromRWord == "FROM" || fromRWord == "IN") {
+				p.pop()
+				database := p.peek()
+				if !isIdentifier(database) {
+					return p.query, fmt.Errorf("at SHOW: expected database name")
+				}
+				p.query.Database = p.identifier(database)
+				p.pop()
+			}
+			if p.i < len(p.sql) {
+				return p.query, fmt.Errorf("at SHOW: expected end of query")
+			}
+		case stepDescribe:
+			tableName := p.peek()
+			if !isIdentifier(tableName) {
+				return p.query, fmt.Errorf("at DESCRIBE: expected table name")
+			}
+			p.setTableName(tableName)
+			p.pop()
+			if p.i < len(p.sql) {
+				return p.query, fmt.Errorf("at DESCRIBE: expected end of query")
+			}
+		case stepPrivileges:
+			privilege := query.Privilege{}
+			for {
+				word := strings.ToUpper(p.peek())
+				if word == "" || word == "," || word == "(" || word == "ON" {
+					break
+				}
+				if !isIdentifier(word) && !isKeyword(word) {
+					return p.query, fmt.Errorf("at %v: expected privilege", p.privilegeRWord())
+				}
+				if privilege.Name != "" {
+					privilege.Name += " "
+				}
+				privilege.Name += word
+				p.pop()
+			}
+			if privilege.Name == "" {
+				return p.query, fmt.Errorf("at %v: expected privilege", p.privilegeRWord())
+			}
+			if p.peek() == "(" {
+				p.pop()
+				for {
+					identifier := p.peek()
+					if !isIdentifier(identifier) {
+						return p.query, fmt.Errorf("at %v: expected field", p.privilegeRWord())
+					}
+					privilege.Fields = append(privilege.Fields, p.identifier(identifier))
+					p.pop()
+					commaOrClosingParens := p.pop()
+					if commaOrClosingParens == ")" {
+						break
+					}
+					if commaOrClosingParens != "," {
+						return p.query, fmt.Errorf("at %v: expected comma or closing parens", p.privilegeRWord())
+					}
+				}
+			}
+			p.query.Privileges = append(p.query.Privileges, privilege)
+			commaOrOn := p.peek()
+			if commaOrOn == "," {
+				p.pop()
+				continue
+			}
+			if commaOrOn != "ON" {
+				return p.query, fmt.Errorf("at %v: expected ON", p.privilegeRWord())
+			}
+			p.pop()
+			p.step = stepPrivilegeObjects
+		case stepPrivilegeObjects:
+			object := query.Object{}
+			switch kind := strings.ToUpper(p.peek()); kind {
+			case "TABLE", "SCHEMA", "DATABASE", "SEQUENCE", "FUNCTION", "PROCEDURE":
+				object.Kind = kind
+				p.pop()
+			case "ALL":
+				p.pop()
+				if strings.ToUpper(p.pop()) != "TABLES" || strings.ToUpper(p.pop()) != "IN" || strings.ToUpper(p.pop()) != "SCHEMA" {
+					return p.query, fmt.Errorf("at %v: expected ALL TABLES IN SCHEMA", p.privilegeRWord())
+				}
+				object.Kind = "ALL TABLES IN SCHEMA"
+			}
+			name := p.peek()
+			if !isIdentifierOrAsterisk(name) && name != "*.*" {
+				return p.query, fmt.Errorf("at %v: expected object name", p.privilegeRWord())
+			}
+			object.Database, object.Name = p.qualifiedName(name)
+			object.Position = p.tokenPosition()
+			p.query.Objects = append(p.query.Objects, object)
+			p.pop()
+			commaOrTo := p.peek()
+			if commaOrTo == "," {
+				p.pop()
+				continue
+			}
+			toRWord := "TO"
+			if p.query.Type == query.Revoke {
+				toRWord = "FROM"
+			}
+			if strings.ToUpper(commaOrTo) != toRWord {
+				return p.query, fmt.Errorf("at %v: expected %v", p.privilegeRWord(), toRWord)
+			}
+			p.pop()
+			p.step = stepGrantees
+		case stepGrantees:
+			grantee, ln := p.peekQuotedStringWithLength()
+			if ln == 0 {
+				grantee = p.peek()
+				if !isIdentifier(grantee) {
+					return p.query, fmt.Errorf("at %v: expected role or user", p.privilegeRWord())
+				}
+				grantee = p.identifier(grantee)
+			}
+			p.pop()
+			// MySQL accounts are written as 'user'@'host'
+			if p.i < len(p.sql) && p.sql[p.i] == '@' {
+				p.i++
+				host, ln := p.peekQuotedStringWithLength()
+				if ln == 0 {
+					host = p.peek()
+				}
+				if host == "" {
+					return p.query, fmt.Errorf("at %v: expected host", p.privilegeRWord())
+				}
+				grantee += "@" + host
+				p.pop()
+			}
+			p.query.Grantees = append(p.query.Grantees, grantee)
+			if p.peek() == "," {
+				p.pop()
+				continue
+			}
+			if p.query.Type == query.Grant && strings.ToUpper(p.peek()) == "WITH" {
+				p.pop()
+				if strings.ToUpper(p.pop()) != "GRANT" || strings.ToUpper(p.pop()) != "OPTION" {
+					return p.query, fmt.Errorf("at GRANT: expected WITH GRANT OPTION")
+				}
+				p.query.GrantOption = true
+			}
+			if p.i < len(p.sql) {
+				return p.query, fmt.Errorf("at %v: expected end of query", p.privilegeRWord())
+			}
+		case stepReturning:
+			returningRWord := strings.ToUpper(p.peek())
+			if returningRWord != "RETURNING" && returningRWord != "OUTPUT" {
+				return p.query, fmt.Errorf("expected RETURNING")
+			}
+			p.query.Output = returningRWord == "OUTPUT"
+			p.pop()
+			p.step = stepReturningField
+		case stepReturningField:
+			start := p.position(p.i, p.i)
+			field, err := p.parseExpr()
+			if err != nil {
+				return p.query, fmt.Errorf("at %v: expected field to return", p.returningRWord())
+			}
+			if strings.ToUpper(p.peek()) == "AS" {
+				p.pop()
+				alias := p.peek()
+				if !isIdentifier(alias) {
+					return p.query, fmt.Errorf("at %v: expected field alias for \"as\"", p.returningRWord())
+				}
+				p.pop()
+				field = query.AliasedExpr{Expr: field, Alias: p.identifier(alias), Position: p.spanFrom(start)}
+			}
+			p.query.Returning = append(p.query.Returning, field)
+			p.step = stepReturningComma
+		case stepReturningComma:
+			commaRWord := strings.ToUpper(p.peek())
+			switch {
+			case commaRWord == ",":
+				p.pop()
+				p.step = stepReturningField
+			case p.query.Output && p.query.Type == query.Insert && commaRWord == "VALUES":
+				p.step = stepInsertValuesRWord
+			case p.query.Output && p.query.Type != query.Insert && commaRWord == "WHERE":
+				p.step = stepWhere
+			case p.query.Output && p.query.Type == query.Update && commaRWord == "FROM" && len(p.query.From) == 0:
+				p.step = stepUpdateFrom
+			default:
+				return p.query, fmt.Errorf("at %v: expected comma", p.returningRWord())
+			}
+		}
+	}
+}
 
-			/// this catches an onduplicate key query and just finishes, that level of complexitiy is beyond the scope of this project
-			if isReservedWord(commaRWord) == false {
-
-				return p.query, nil
+// setTableName sets the query's table name, splitting off the database if it's qualified as in "db.table", and its
+// position, which is the next token's.
+func (p *parser) setTableName(tableName string) {
+	database, tableName := p.qualifiedName(tableName)
+	if database != "" {
+		p.query.Database = database
+	}
+	p.query.TableName = tableName
+	p.query.TablePosition = p.tokenPosition()
+}
 
-			} else {
+// qualifiedName splits an identifier token like "db.table" into its database and name parts; database is empty if
+// unqualified.
+func (p *parser) qualifiedName(token string) (string, string) {
+	parts := p.identifierParts(token)
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+	return parts[0], strings.Join(parts[1:], ".")
+}
 
-				p.step = stepInsertValuesOpeningParens
+// identifier returns the name an identifier token stands for, with its quoted parts double-quoted whatever quotes
+// they were written with, e.g. db."my table" for `db`.[my table].
+func (p *parser) identifier(token string) string {
+	return strings.Join(p.identifierParts(token), ".")
+}
 
+// identifierParts splits an identifier token at the dots outside quotes, double-quoting the quoted parts.
+func (p *parser) identifierParts(token string) []string {
+	parts := []string{}
+	part := []byte{}
+	for i := 0; i < len(token); {
+		closing := closingIdentifierQuote(token[i])
+		switch {
+		case closing != 0:
+			end := quotedIdentifierEnd(token, i)
+			part = append(part, '"')
+			for j := i + 1; j < end-1; j++ {
+				if token[j] == closing {
+					// a doubled closing quote, as in "a""b"
+					j++
+				}
+				if token[j] == '"' {
+					part = append(part, '"')
+				}
+				part = append(part, token[j])
 			}
+			part = append(part, '"')
+			i = end
+		case token[i] == '.':
+			parts = append(parts, string(part))
+			part = []byte{}
+			i++
+		default:
+			part = append(part, token[i])
+			i++
 		}
 	}
+	return append(parts, string(part))
+}
+
+// privilegeRWord is the keyword the current privilege statement starts with, for error messages.
+func (p *parser) privilegeRWord() string {
+	if p.query.Type == query.Revoke {
+		return "REVOKE"
+	}
+	return "GRANT"
+}
+
+// isSetAssignment reports whether the upcoming tokens start a new "variable = value" assignment in a SET statement.
+func (p *parser) isSetAssignment() bool {
+	i := p.i
+	defer func() { p.i = i }()
+	identifier := p.pop()
+	equalsRWord := strings.ToUpper(p.peek())
+	return isIdentifier(identifier) && (equalsRWord == "=" || equalsRWord == "TO")
+}
+
+// returningRWord is the keyword the current RETURNING list was introduced with, for error messages.
+func (p *parser) returningRWord() string {
+	if p.query.Output {
+		return "OUTPUT"
+	}
+	return "RETURNING"
 }
 
 func (p *parser) peek() string {
@@ -552,57 +1407,333 @@ func (p *parser) peek() string {
 
 func (p *parser) pop() string {
 	peeked, len := p.peekWithLength()
+	if strings.ContainsRune(peeked, ' ') && p.reservedWordLength(peeked) == len {
+		// comments between the words of a keyword, as in "ORDER /* by a */ BY"
+		for i := p.i; i < p.i+len; i++ {
+			if ln := commentLength(p.sql[i:]); ln > 0 {
+				p.addComment(i, i+ln)
+				i += ln - 1
+			}
+		}
+	}
 	p.i += len
+	p.lastEnd = p.i
 	p.popWhitespace()
 	return peeked
 }
 
+// position returns the Position of sql[start:end].
+func (p *parser) position(start, end int) query.Position {
+	if p.line == 0 {
+		p.line = 1
+	}
+	for ; p.lineOffset < start; p.lineOffset++ {
+		if p.sql[p.lineOffset] == '\n' {
+			p.line, p.lineStart = p.line+1, p.lineOffset+1
+		}
+	}
+	for p.lineOffset > start {
+		p.lineOffset--
+		if p.sql[p.lineOffset] == '\n' {
+			p.line, p.lineStart = p.line-1, strings.LastIndexByte(p.sql[:p.lineOffset], '\n')+1
+		}
+	}
+	return query.Position{Start: start, End: end, Line: p.line, Column: start - p.lineStart + 1}
+}
+
+// spanFrom returns the position from start to the end of the last token popped.
+func (p *parser) spanFrom(start query.Position) query.Position {
+	start.End = p.lastEnd
+	return start
+}
+
+// tokenPosition returns the Position of the next token.
+func (p *parser) tokenPosition() query.Position {
+	_, ln := p.peekWithLength()
+	return p.position(p.i, p.i+ln)
+}
+
+// popWhitespace skips whitespace and comments, recording the comments on the query.
 func (p *parser) popWhitespace() {
-	for ; p.i < len(p.sql) && p.sql[p.i] == ' '; p.i++ {
+	for p.i < len(p.sql) {
+		if isSpace(p.sql[p.i]) {
+			p.i++
+			continue
+		}
+		end := p.commentEnd()
+		if end == p.i {
+			return
+		}
+		p.addComment(p.i, end)
+		p.i = end
+	}
+}
+
+// commentEnd returns the end of a comment starting at the current position, or the current position if there's none.
+func (p *parser) commentEnd() int {
+	ln := commentLength(p.sql[p.i:])
+	if ln < 0 {
+		p.err = fmt.Errorf("unterminated comment")
+		return len(p.sql)
+	}
+	return p.i + ln
+}
+
+// commentLength returns the length of the comment at the start of s, 0 if there's none, or -1 if it's unterminated.
+func commentLength(s string) int {
+	switch {
+	case strings.HasPrefix(s, "--") || strings.HasPrefix(s, "#"):
+		if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+			return nl
+		}
+		return len(s)
+	case strings.HasPrefix(s, "/*"):
+		if end := strings.Index(s[2:], "*/"); end >= 0 {
+			return 2 + end + 2
+		}
+		return -1
 	}
+	return 0
+}
 
+func (p *parser) addComment(start, end int) {
+	if start <= p.lastComment {
+		return
+	}
+	p.lastComment = start
+	comment := query.Comment{Text: p.sql[start:end], Start: start, End: end}
+	if strings.HasPrefix(comment.Text, "/*+") {
+		p.query.Hints = append(p.query.Hints, comment)
+		return
+	}
+	p.query.Comments = append(p.query.Comments, comment)
 }
 
-var reservedWords = []string{"(", ")", ">=", "<=", "!=", ",", "=", ">", "<", "SELECT", "TOP", "INSERT INTO", "VALUES", "UPDATE", "DELETE FROM", "WHERE", "FROM", "SET", "ON DUPLICATE KEY UPDATE", "ORDER BY", "ASC", "DESC", "LEFT JOIN", "RIGHT JOIN", "INNER JOIN", "JOIN", "ON", "AS"}
+var reservedWords = []string{"(", ")", ">=", "<=", "!=", ",", "=", ">", "<", "||", "+", "-", "/", "%", "SELECT", "TOP", "INSERT INTO", "VALUES", "UPDATE", "DELETE FROM", "DELETE", "WHERE", "FROM", "SET", "ON DUPLICATE KEY UPDATE", "ORDER BY", "ASC", "DESC", "LEFT JOIN", "RIGHT JOIN", "INNER JOIN", "JOIN", "ON", "AS", "RETURNING", "OUTPUT", "USING", "LIMIT"}
 
-var reservedWordsOnly = []string{"SELECT", "TOP", "INSERT INTO", "VALUES", "UPDATE", "DELETE FROM", "WHERE", "FROM", "SET", "ON DUPLICATE KEY UPDATE", "ORDER BY", "ASC", "DESC", "LEFT JOIN", "RIGHT JOIN", "INNER JOIN", "JOIN", "ON", "AS"}
+var reservedWordsOnly = []string{"SELECT", "TOP", "INSERT INTO", "VALUES", "UPDATE", "DELETE FROM", "DELETE", "WHERE", "FROM", "SET", "ON DUPLICATE KEY UPDATE", "ORDER BY", "ASC", "DESC", "LEFT JOIN", "RIGHT JOIN", "INNER JOIN", "JOIN", "ON", "AS", "RETURNING", "OUTPUT", "USING", "LIMIT"}
 
 func (p *parser) peekWithLength() (string, int) {
 	if p.i >= len(p.sql) {
 		return "", 0
 	}
 	for _, rWord := range reservedWords {
-		token := strings.ToUpper(p.sql[p.i:min(len(p.sql), p.i+len(rWord))])
-		if token == rWord {
-			return token, len(token)
+		if ln := p.reservedWordLength(rWord); ln > 0 {
+			return rWord, ln
 		}
 	}
-	if p.sql[p.i] == '\'' { // Quoted string
-		return p.peekQuotedStringWithLength()
+	if quotedValue, ln := p.peekQuotedStringWithLength(); ln > 0 {
+		return quotedValue, ln
+	}
+	if placeholder, ln := p.peekPlaceholderWithLength(); ln > 0 {
+		return placeholder, ln
+	}
+	if _, ln := numberLength(p.sql[p.i:]); ln > 0 {
+		return p.sql[p.i : p.i+ln], ln
 	}
 
 	return p.peekIdentifierWithLength()
 }
 
+// reservedWordLength returns the length of rWord at the current position, or 0 if it isn't there. Words in rWord
+// may be separated by any whitespace and comments, e.g. "ORDER\n  BY" or "INSERT /*+ SET_VAR(a=1) */ INTO".
+func (p *parser) reservedWordLength(rWord string) int {
+	i := p.i
+	for j := 0; j < len(rWord); j++ {
+		if rWord[j] == ' ' {
+			from := i
+			for i < len(p.sql) {
+				if isSpace(p.sql[i]) {
+					i++
+				} else if ln := commentLength(p.sql[i:]); ln > 0 {
+					i += ln
+				} else {
+					break
+				}
+			}
+			if i == from {
+				return 0
+			}
+			continue
+		}
+		if i >= len(p.sql) || toUpper(p.sql[i]) != rWord[j] {
+			return 0
+		}
+		i++
+	}
+	// words must end at a word boundary, so that e.g. "asset" isn't read as "AS"
+	if isIdentifierChar(rWord[len(rWord)-1]) && i < len(p.sql) && isIdentifierChar(p.sql[i]) {
+		return 0
+	}
+	return i - p.i
+}
+
+// peekQuotedStringWithLength peeks a string literal, returning its value. Quotes are escaped by doubling them, and
+// with backslashes in MySQL and in Postgres E'...' escape strings. N'...' national strings and Postgres dollar-quoted
+// strings like $$it's$$ are strings too.
 func (p *parser) peekQuotedStringWithLength() (string, int) {
-	if len(p.sql) < p.i || p.sql[p.i] != '\'' {
+	i := p.i
+	if i >= len(p.sql) {
+		return "", 0
+	}
+	if p.sql[i] == '$' {
+		tag, _ := dollarTag(p.sql[i:], true)
+		if tag == "" {
+			return "", 0
+		}
+		end := strings.Index(p.sql[i+len(tag):], tag)
+		if end < 0 {
+			return "", 0
+		}
+		return p.sql[i+len(tag) : i+len(tag)+end], len(tag) + end + len(tag)
+	}
+	backslashes := p.dialect == MySQL
+	if prefix := toUpper(p.sql[i]); (prefix == 'E' || prefix == 'N') && i+1 < len(p.sql) && p.sql[i+1] == '\'' {
+		backslashes = backslashes || prefix == 'E'
+		i++
+	}
+	if p.sql[i] != '\'' {
+		return "", 0
+	}
+	value := []byte{}
+	for i++; i < len(p.sql); i++ {
+		c := p.sql[i]
+		switch {
+		case c == '\\' && backslashes && i+1 < len(p.sql):
+			i++
+			value = append(value, unescape(p.sql[i])...)
+		case c == '\'' && i+1 < len(p.sql) && p.sql[i+1] == '\'':
+			value = append(value, c)
+			i++
+		case c == '\'':
+			return string(value), i + 1 - p.i
+		default:
+			value = append(value, c)
+		}
+	}
+	return "", 0
+}
+
+// unescape returns what a backslash followed by c stands for in a string.
+func unescape(c byte) []byte {
+	switch c {
+	case '0':
+		return []byte{0}
+	case 'b':
+		return []byte{'\b'}
+	case 'f':
+		return []byte{'\f'}
+	case 'n':
+		return []byte{'\n'}
+	case 'r':
+		return []byte{'\r'}
+	case 't':
+		return []byte{'\t'}
+	case 'Z':
+		return []byte{26}
+	case '%', '_':
+		// kept escaped, for LIKE patterns
+		return []byte{'\\', c}
+	}
+	return []byte{c}
+}
+
+// rawString returns the text of the string literal of length ln at the current position, if writing its value in
+// single quotes wouldn't give back the same text; otherwise it's empty.
+func (p *parser) rawString(value string, ln int) string {
+	raw := p.sql[p.i : p.i+ln]
+	if raw == "'"+value+"'" {
+		return ""
+	}
+	return raw
+}
+
+// peekPlaceholderWithLength peeks a bind parameter placeholder: "?", "$1" or ":name".
+func (p *parser) peekPlaceholderWithLength() (string, int) {
+	if p.i >= len(p.sql) {
 		return "", 0
 	}
-	for i := p.i + 1; i < len(p.sql); i++ {
-		if p.sql[i] == '\'' {
-			return p.sql[p.i+1 : i], len(p.sql[p.i+1:i]) + 2 // +2 for the two quotes
+	switch p.sql[p.i] {
+	case '?':
+		return "?", 1
+	case '$', ':':
+		i := p.i + 1
+		for ; i < len(p.sql) && isIdentifierChar(p.sql[i]); i++ {
+			if p.sql[p.i] == '$' && (p.sql[i] < '0' || p.sql[i] > '9') {
+				return "", 0
+			}
+		}
+		if i == p.i+1 || (p.sql[p.i] != '$' && p.sql[p.i+1] >= '0' && p.sql[p.i+1] <= '9') {
+			return "", 0
 		}
+		return p.sql[p.i:i], i - p.i
 	}
 	return "", 0
 }
 
+// peekIdentifierWithLength peeks an identifier, which may be qualified, as in "db.table", and whose parts may be
+// quoted with backticks, double quotes or brackets, as in `db`."my table". Quoted parts are kept with their quotes.
 func (p *parser) peekIdentifierWithLength() (string, int) {
-	for i := p.i; i < len(p.sql); i++ {
-		if matched, _ := regexp.MatchString(`[\.\-a-zA-Z0-9_*]`, string(p.sql[i])); !matched {
-			return p.sql[p.i:i], len(p.sql[p.i:i])
+	i := p.i
+	// MySQL user variables like @x and system variables like @@sql_mode
+	for i < len(p.sql) && i < p.i+2 && p.sql[i] == '@' {
+		i++
+	}
+	for i < len(p.sql) {
+		c := p.sql[i]
+		if closingIdentifierQuote(c) != 0 {
+			end := quotedIdentifierEnd(p.sql, i)
+			if p.sql[end-1] != closingIdentifierQuote(c) || end == i+1 {
+				p.err = fmt.Errorf("unterminated quoted identifier")
+			}
+			i = end
+			continue
+		}
+		if c == '*' {
+			// "*" is a token on its own, or the last part of a name like t.* or *.*, never inside one like b*2
+			if i > p.i && p.sql[i-1] != '.' {
+				break
+			}
+			i++
+			if i < len(p.sql) && p.sql[i] == '.' {
+				continue
+			}
+			break
+		}
+		if !isIdentifierChar(c) && c != '.' {
+			break
 		}
+		i++
 	}
-	return p.sql[p.i:], len(p.sql[p.i:])
+	return p.sql[p.i:i], i - p.i
+}
+
+// closingIdentifierQuote returns the quote closing an identifier quoted with c, or 0 if c doesn't quote identifiers.
+func closingIdentifierQuote(c byte) byte {
+	switch c {
+	case '`', '"':
+		return c
+	case '[':
+		return ']'
+	}
+	return 0
+}
+
+// quotedIdentifierEnd returns the end of the quoted identifier starting at i, just after its closing quote, or the end
+// of s if it's unterminated. A doubled closing quote, as in "a""b", doesn't close it.
+func quotedIdentifierEnd(s string, i int) int {
+	closing := closingIdentifierQuote(s[i])
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != closing {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == closing {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(s)
 }
 
 func (p *parser) validate() error {
@@ -612,9 +1743,45 @@ func (p *parser) validate() error {
 	if p.query.Type == query.UnknownType {
 		return fmt.Errorf("query type cannot be empty")
 	}
-	if p.query.TableName == "" {
+	if p.query.TableName == "" && p.query.Type.HasTable() {
 		return fmt.Errorf("table name cannot be empty")
 	}
+	if p.query.Type == query.Set {
+		if len(p.query.Updates) == 0 {
+			return fmt.Errorf("at SET: expected variable to set")
+		}
+		for _, u := range p.query.Updates {
+			if len(u.Values) == 0 {
+				return fmt.Errorf("at SET: expected value for %v", u.Fields[0])
+			}
+		}
+	}
+	if p.query.Savepoint == "" && (p.query.Type == query.Savepoint || p.query.Type == query.Release || p.step == stepSavepointName) {
+		return fmt.Errorf("at SAVEPOINT: expected savepoint name")
+	}
+	if p.query.Database == "" && p.query.Type == query.Use {
+		return fmt.Errorf("at USE: expected database name")
+	}
+	if p.query.Explained == nil && p.query.Type == query.Explain {
+		return fmt.Errorf("at EXPLAIN: expected query to explain")
+	}
+	if p.query.Show == "" && p.query.Type == query.Show {
+		return fmt.Errorf("at SHOW: expected what to show")
+	}
+	if p.query.TableName == "" && p.query.Type == query.Describe {
+		return fmt.Errorf("at DESCRIBE: expected table name")
+	}
+	if p.query.Type == query.Grant || p.query.Type == query.Revoke {
+		if len(p.query.Privileges) == 0 {
+			return fmt.Errorf("at %v: expected privilege", p.privilegeRWord())
+		}
+		if len(p.query.Objects) == 0 {
+			return fmt.Errorf("at %v: expected ON", p.privilegeRWord())
+		}
+		if len(p.query.Grantees) == 0 {
+			return fmt.Errorf("at %v: expected role or user", p.privilegeRWord())
+		}
+	}
 	if len(p.query.Conditions) == 0 && (p.query.Type == query.Update || p.query.Type == query.Delete) {
 		return fmt.Errorf("at WHERE: WHERE clause is mandatory for UPDATE & DELETE")
 	}
@@ -629,12 +1796,21 @@ func (p *parser) validate() error {
 			return fmt.Errorf("at WHERE: condition with empty right side operand")
 		}
 	}
+	if p.step == stepReturningField {
+		return fmt.Errorf("at %v: expected field to return", p.returningRWord())
+	}
 	if p.query.Type == query.Insert && len(p.query.Inserts) == 0 {
 		return fmt.Errorf("at INSERT INTO: need at least one row to insert")
 	}
+	if p.step == stepInsertOnDuplicateField {
+		return fmt.Errorf("at ON DUPLICATE KEY UPDATE: expected field to update")
+	}
+	if p.step == stepInsertOnDuplicateValue {
+		return fmt.Errorf("at ON DUPLICATE KEY UPDATE: expected value for %v", p.query.Updates[len(p.query.Updates)-1].Fields[0])
+	}
 	if p.query.Type == query.Insert {
 		for _, i := range p.query.Inserts {
-			if len(i) != len(p.query.Fields) {
+			if (p.query.Fields != nil && len(i) != len(p.query.Fields)) || len(i) != len(p.query.Inserts[0]) {
 				return fmt.Errorf("at INSERT INTO: value count doesn't match field count")
 			}
 		}
@@ -652,6 +1828,10 @@ func (p *parser) logError() {
 }
 
 func isIdentifier(s string) bool {
+	if s != "" && closingIdentifierQuote(s[0]) != 0 {
+		// quoted identifiers may be anything, even reserved words
+		return true
+	}
 	for _, rw := range reservedWords {
 		if strings.ToUpper(s) == rw {
 			return false
@@ -661,14 +1841,116 @@ func isIdentifier(s string) bool {
 	return matched
 }
 
-func isReservedWord(s string) bool {
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func toUpper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isKeyword(s string) bool {
 	for _, rw := range reservedWordsOnly {
 		if strings.ToUpper(s) == rw {
-			return false
+			return true
 		}
 	}
-	matched, _ := regexp.MatchString("[a-zA-Z_][a-zA-Z_0-9]*", s)
-	return matched
+	return false
+}
+
+// column splits a possibly table-qualified identifier token like "inserted.id" into a query.Column.
+func (p *parser) column(token string) query.Column {
+	parts := p.identifierParts(token)
+	return query.Column{Table: strings.Join(parts[:len(parts)-1], "."), Name: parts[len(parts)-1]}
+}
+
+// literalKind classifies an unquoted value; it's an UnknownLiteral if it isn't a literal at all, e.g. a field name.
+func literalKind(s string) query.LiteralKind {
+	if isPlaceholder(s) {
+		return query.PlaceholderLiteral
+	}
+	if kind, ln := numberLength(s); ln > 0 && ln == len(s) {
+		return kind
+	}
+	if kind, ln := numberLength(strings.TrimPrefix(s, "-")); ln > 0 && ln == len(s)-1 {
+		return kind
+	}
+	switch strings.ToUpper(s) {
+	case "NULL":
+		return query.NullLiteral
+	case "TRUE", "FALSE":
+		return query.BoolLiteral
+	}
+	return query.UnknownLiteral
+}
+
+// numberLength returns the kind and length of the number at the start of s, if there's one: an integer like 42 or 0xFF,
+// a decimal like 19.99 or .5, or a float like 1e10 or 2.5E-3. Numbers run into by letters or dots, as in "1st" or
+// "1.2.3", aren't numbers.
+func numberLength(s string) (query.LiteralKind, int) {
+	digits := func(i int, isDigit func(byte) bool) int {
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		return i
+	}
+	kind := query.IntegerLiteral
+	i := 0
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') && isHexDigit(s[2]) {
+		i = digits(2, isHexDigit)
+	} else {
+		i = digits(0, isDigit)
+		if i < len(s) && s[i] == '.' && i+1 < len(s) && isDigit(s[i+1]) {
+			kind = query.DecimalLiteral
+			i = digits(i+1, isDigit)
+		} else if i > 0 && i < len(s) && s[i] == '.' && (i+1 == len(s) || !isIdentifierChar(s[i+1])) {
+			// e.g. "5."
+			kind = query.DecimalLiteral
+			i++
+		}
+		if i == 0 {
+			return query.UnknownLiteral, 0
+		}
+		if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+			j := i + 1
+			if j < len(s) && (s[j] == '+' || s[j] == '-') {
+				j++
+			}
+			if j < len(s) && isDigit(s[j]) {
+				kind = query.FloatLiteral
+				i = digits(j, isDigit)
+			}
+		}
+	}
+	if i < len(s) && (isIdentifierChar(s[i]) || s[i] == '.') {
+		return query.UnknownLiteral, 0
+	}
+	return kind, i
+}
+
+func isNumber(kind query.LiteralKind) bool {
+	return kind == query.IntegerLiteral || kind == query.DecimalLiteral || kind == query.FloatLiteral
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isPlaceholder(s string) bool {
+	p := parser{sql: s}
+	_, ln := p.peekPlaceholderWithLength()
+	return ln > 0 && ln == len(s)
 }
 
 func isIdentifierOrAsterisk(s string) bool {
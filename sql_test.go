@@ -1,12 +1,18 @@
 package sqlparser
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"text/template"
+	"time"
 
 	"github.com/spasticus74/sqlparser/query"
 	"github.com/stretchr/testify/require"
@@ -83,6 +89,107 @@ func TestSQL(t *testing.T) {
 			},
 			Err: nil,
 		},
+		{
+			Name: "SELECT with quoted identifiers works",
+			SQL:  "SELECT `Order Id`, \"select\" AS \"My \"\"Alias\"\"\", [Total] FROM [Sales].`Order Details` WHERE `Order Id` = '`1`'",
+			Expected: query.Query{
+				Type:       query.Select,
+				Database:   `"Sales"`,
+				TableName:  `"Order Details"`,
+				Fields:     []string{`"Order Id"`, `"select"`, `"Total"`},
+				Aliases:    map[string]string{`"select"`: `"My ""Alias"""`},
+				Conditions: []query.Condition{{Operand1: `"Order Id"`, Operand1IsField: true, Operator: query.Eq, Operand2: "`1`", Operand2Kind: query.StringLiteral}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SELECT with quoted qualified identifiers works",
+			SQL:  "SELECT t.\"a.b\" FROM \"db\".t WHERE t.[c] = \"t\".d",
+			Expected: query.Query{
+				Type:       query.Select,
+				Database:   `"db"`,
+				TableName:  "t",
+				Fields:     []string{`t."a.b"`},
+				Conditions: []query.Condition{{Operand1: `t."c"`, Operand1IsField: true, Operator: query.Eq, Operand2: `"t".d`, Operand2IsField: true}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SELECT with the same name quoted and bare works",
+			SQL:  "SELECT a FROM t WHERE \"Foo\" = 1 AND Foo = 2",
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "t",
+				Fields:    []string{"a"},
+				Conditions: []query.Condition{
+					{Operand1: `"Foo"`, Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2Kind: query.IntegerLiteral},
+					{Operand1: "Foo", Operand1IsField: true, Operator: query.Eq, Operand2: "2", Operand2Kind: query.IntegerLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "SELECT with unterminated quoted identifier fails",
+			SQL:      "SELECT a FROM \"b",
+			Expected: query.Query{Type: query.Select, TableName: `"b"`, Fields: []string{"a"}},
+			Err:      fmt.Errorf("unterminated quoted identifier"),
+		},
+		{
+			Name: "SELECT with escaped quotes works",
+			SQL:  "SELECT a FROM 'b' WHERE c = 'O''Brien' AND d = 'C:\\'",
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "b",
+				Fields:    []string{"a"},
+				Conditions: []query.Condition{
+					{Operand1: "c", Operand1IsField: true, Operator: query.Eq, Operand2: "O'Brien", Operand2Kind: query.StringLiteral, Operand2Raw: "'O''Brien'"},
+					{Operand1: "d", Operand1IsField: true, Operator: query.Eq, Operand2: "C:\\", Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SELECT with negative number works",
+			SQL:  "SELECT a FROM 'b' WHERE c = -.5 AND d > -3",
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "b",
+				Fields:    []string{"a"},
+				Conditions: []query.Condition{
+					{Operand1: "c", Operand1IsField: true, Operator: query.Eq, Operand2: "-.5", Operand2Kind: query.DecimalLiteral},
+					{Operand1: "d", Operand1IsField: true, Operator: query.Gt, Operand2: "-3", Operand2Kind: query.IntegerLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SELECT with WHERE with expressions works",
+			SQL:  "SELECT a FROM 'b' WHERE c = -d AND e = 1-2",
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "b",
+				Fields:    []string{"a"},
+				Conditions: []query.Condition{
+					{Operand1: "c", Operand1IsField: true, Operator: query.Eq, Operand2Expr: query.UnaryExpr{Operator: "-", Operand: query.Column{Name: "d"}}},
+					{Operand1: "e", Operand1IsField: true, Operator: query.Eq, Operand2Expr: query.BinaryExpr{
+						Left: query.Literal{Kind: query.IntegerLiteral, Value: "1"}, Operator: "-", Right: query.Literal{Kind: query.IntegerLiteral, Value: "2"},
+					}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "SELECT with dangling minus fails",
+			SQL:      "SELECT a FROM 'b' WHERE c = -",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at WHERE: expected quoted value"),
+		},
+		{
+			Name:     "SELECT with malformed number fails",
+			SQL:      "SELECT a FROM 'b' WHERE c = 1.2.3",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at WHERE: malformed number 1.2.3"),
+		},
 		{
 			Name:     "SELECT with empty WHERE fails",
 			SQL:      "SELECT a, c, d FROM 'b' WHERE",
@@ -103,7 +210,7 @@ func TestSQL(t *testing.T) {
 				TableName: "b",
 				Fields:    []string{"a", "c", "d"},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -116,7 +223,7 @@ func TestSQL(t *testing.T) {
 				TableName: "b",
 				Fields:    []string{"a", "c", "d"},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Lt, Operand2: "1", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Lt, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -129,7 +236,7 @@ func TestSQL(t *testing.T) {
 				TableName: "b",
 				Fields:    []string{"a", "c", "d"},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Lte, Operand2: "1", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Lte, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -142,7 +249,7 @@ func TestSQL(t *testing.T) {
 				TableName: "b",
 				Fields:    []string{"a", "c", "d"},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Gt, Operand2: "1", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Gt, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -155,7 +262,7 @@ func TestSQL(t *testing.T) {
 				TableName: "b",
 				Fields:    []string{"a", "c", "d"},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Gte, Operand2: "1", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Gte, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -168,7 +275,7 @@ func TestSQL(t *testing.T) {
 				TableName: "b",
 				Fields:    []string{"a", "c", "d"},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Ne, Operand2: "1", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Ne, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -203,8 +310,8 @@ func TestSQL(t *testing.T) {
 				TableName: "b",
 				Fields:    []string{"a", "c", "d"},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Ne, Operand2: "1", Operand2IsField: false},
-					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "2", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Ne, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "2", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -257,9 +364,11 @@ func TestSQL(t *testing.T) {
 			Expected: query.Query{
 				Type:      query.Update,
 				TableName: "a",
-				Updates:   map[string]string{"b": "hello"},
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "hello"}}},
+				},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -270,9 +379,12 @@ func TestSQL(t *testing.T) {
 			Expected: query.Query{
 				Type:      query.Update,
 				TableName: "a",
-				Updates:   map[string]string{"b": "hello", "c": "bye"},
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "hello"}}},
+					{Fields: []string{"c"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "bye"}}},
+				},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false},
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
 				},
 			},
 			Err: nil,
@@ -283,10 +395,124 @@ func TestSQL(t *testing.T) {
 			Expected: query.Query{
 				Type:      query.Update,
 				TableName: "a",
-				Updates:   map[string]string{"b": "hello", "c": "bye"},
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "hello"}}},
+					{Fields: []string{"c"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "bye"}}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "789", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "UPDATE with lowercase set and expressions works",
+			SQL:  "update 'a' set count = count + 1, total = price * (qty - 2), seen = NOW() where a = '1'",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Updates: []query.Assignment{
+					{Fields: []string{"count"}, Values: []query.Expr{
+						query.BinaryExpr{Left: query.Column{Name: "count"}, Operator: "+", Right: query.Literal{Kind: query.IntegerLiteral, Value: "1"}},
+					}},
+					{Fields: []string{"total"}, Values: []query.Expr{
+						query.BinaryExpr{
+							Left:     query.Column{Name: "price"},
+							Operator: "*",
+							Right:    query.BinaryExpr{Left: query.Column{Name: "qty"}, Operator: "-", Right: query.Literal{Kind: query.IntegerLiteral, Value: "2"}},
+						},
+					}},
+					{Fields: []string{"seen"}, Values: []query.Expr{query.FuncCall{Name: "NOW"}}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "UPDATE with multiplication without spaces works",
+			SQL:  "UPDATE 'a' SET b = 2*c, d = c*2 WHERE a = '1'",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{
+						query.BinaryExpr{Left: query.Literal{Kind: query.IntegerLiteral, Value: "2"}, Operator: "*", Right: query.Column{Name: "c"}},
+					}},
+					{Fields: []string{"d"}, Values: []query.Expr{
+						query.BinaryExpr{Left: query.Column{Name: "c"}, Operator: "*", Right: query.Literal{Kind: query.IntegerLiteral, Value: "2"}},
+					}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "SELECT with multiplication in a field fails",
+			SQL:      "SELECT b*2 FROM 'a'",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at SELECT: expected comma or FROM"),
+		},
+		{
+			Name: "UPDATE with tuple assignment works",
+			SQL:  "UPDATE 'a' SET (b, c) = ('1', NULL) WHERE a = '1'",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Updates: []query.Assignment{
+					{Fields: []string{"b", "c"}, Values: []query.Expr{
+						query.Literal{Kind: query.StringLiteral, Value: "1"},
+						query.Literal{Kind: query.NullLiteral, Value: "NULL"},
+					}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "UPDATE with mismatched tuple assignment fails",
+			SQL:      "UPDATE 'a' SET (b, c) = ('1') WHERE a = '1'",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at UPDATE: value count doesn't match field count"),
+		},
+		{
+			Name: "UPDATE with JOIN works",
+			SQL:  "UPDATE a JOIN b ON a.id = b.a_id SET a.x = b.y WHERE a.z = '1'",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Joins: []query.Join{
+					{Type: "JOIN", Table: "b", Conditions: []query.JoinCondition{
+						{Table1: "a", Operand1: "id", Operator: query.Eq, Table2: "b", Operand2: "a_id"},
+					}},
+				},
+				Updates: []query.Assignment{
+					{Fields: []string{"a.x"}, Values: []query.Expr{query.Column{Table: "b", Name: "y"}}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "a.z", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "UPDATE with FROM works",
+			SQL:  "UPDATE a SET x = b.y FROM b, c WHERE a.id = b.id",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Updates: []query.Assignment{
+					{Fields: []string{"x"}, Values: []query.Expr{query.Column{Table: "b", Name: "y"}}},
+				},
+				From: []string{"b", "c"},
 				Conditions: []query.Condition{
-					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false},
-					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "789", Operand2IsField: false},
+					{Operand1: "a.id", Operand1IsField: true, Operator: query.Eq, Operand2: "b.id", Operand2IsField: true},
 				},
 			},
 			Err: nil,
@@ -322,11 +548,111 @@ func TestSQL(t *testing.T) {
 				Type:      query.Delete,
 				TableName: "a",
 				Conditions: []query.Condition{
-					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false},
+					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "DELETE with USING works",
+			SQL:  "DELETE FROM 'a' USING b, c WHERE a.id = b.id",
+			Expected: query.Query{
+				Type:      query.Delete,
+				TableName: "a",
+				Using:     []string{"b", "c"},
+				Conditions: []query.Condition{
+					{Operand1: "a.id", Operand1IsField: true, Operator: query.Eq, Operand2: "b.id", Operand2IsField: true},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "Multi-table DELETE with JOIN works",
+			SQL:  "DELETE a, b FROM a JOIN b ON a.id = b.a_id WHERE a.c = '1'",
+			Expected: query.Query{
+				Type:      query.Delete,
+				TableName: "a",
+				Targets:   []string{"a", "b"},
+				Joins: []query.Join{
+					{Type: "JOIN", Table: "b", Conditions: []query.JoinCondition{
+						{Table1: "a", Operand1: "id", Operator: query.Eq, Table2: "b", Operand2: "a_id"},
+					}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "a.c", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "Multi-table DELETE without FROM fails",
+			SQL:      "DELETE a WHERE b = '1'",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at DELETE: expected FROM"),
+		},
+		{
+			Name: "DELETE with ORDER BY and LIMIT works",
+			SQL:  "DELETE FROM 'a' WHERE b = '1' ORDER BY id DESC LIMIT 100",
+			Expected: query.Query{
+				Type:      query.Delete,
+				TableName: "a",
+				Conditions: []query.Condition{
+					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+				OrderFields: []string{"id"},
+				OrderDir:    []string{"DESC"},
+				MaxRows:     100,
+				Limit:       true,
+			},
+			Err: nil,
+		},
+		{
+			Name: "SELECT with LIMIT works",
+			SQL:  "SELECT a FROM 'b' LIMIT 10",
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "b",
+				Fields:    []string{"a"},
+				MaxRows:   10,
+				Limit:     true,
+			},
+			Err: nil,
+		},
+		{
+			Name: "SELECT with JOIN and LIMIT works",
+			SQL:  "SELECT a FROM t JOIN u ON t.id = u.t_id LIMIT 5",
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "t",
+				Fields:    []string{"a"},
+				Joins: []query.Join{
+					{Type: "JOIN", Table: "u", Conditions: []query.JoinCondition{
+						{Table1: "t", Operand1: "id", Operator: query.Eq, Table2: "u", Operand2: "t_id"},
+					}},
 				},
+				MaxRows: 5,
+				Limit:   true,
 			},
 			Err: nil,
 		},
+		{
+			Name:     "SELECT with LIMIT and OFFSET fails",
+			SQL:      "SELECT a FROM 'b' LIMIT 10 OFFSET 5",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at LIMIT: OFFSET is not supported"),
+		},
+		{
+			Name:     "DELETE with non-numeric LIMIT fails",
+			SQL:      "DELETE FROM 'a' WHERE b = '1' LIMIT many",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at LIMIT: expected number of rows"),
+		},
+		{
+			Name:     "SELECT with TOP out of range fails",
+			SQL:      "SELECT TOP 99999999999999999999 a FROM 'b'",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at TOP: expected number of rows"),
+		},
 		{
 			Name:     "Empty INSERT fails",
 			SQL:      "INSERT INTO",
@@ -376,7 +702,7 @@ func TestSQL(t *testing.T) {
 				Type:      query.Insert,
 				TableName: "a",
 				Fields:    []string{"b"},
-				Inserts:   [][]string{{"1"}},
+				Inserts:   [][]query.Expr{{query.Literal{Kind: query.StringLiteral, Value: "1"}}},
 			},
 			Err: nil,
 		},
@@ -393,7 +719,9 @@ func TestSQL(t *testing.T) {
 				Type:      query.Insert,
 				TableName: "a",
 				Fields:    []string{"b", "c", "d"},
-				Inserts:   [][]string{{"1", "2", "3"}},
+				Inserts: [][]query.Expr{
+					{query.Literal{Kind: query.StringLiteral, Value: "1"}, query.Literal{Kind: query.StringLiteral, Value: "2"}, query.Literal{Kind: query.StringLiteral, Value: "3"}},
+				},
 			},
 			Err: nil,
 		},
@@ -404,36 +732,1555 @@ func TestSQL(t *testing.T) {
 				Type:      query.Insert,
 				TableName: "a",
 				Fields:    []string{"b", "c", "d"},
-				Inserts:   [][]string{{"1", "2", "3"}, {"4", "5", "6"}},
+				Inserts: [][]query.Expr{
+					{query.Literal{Kind: query.StringLiteral, Value: "1"}, query.Literal{Kind: query.StringLiteral, Value: "2"}, query.Literal{Kind: query.StringLiteral, Value: "3"}},
+					{query.Literal{Kind: query.StringLiteral, Value: "4"}, query.Literal{Kind: query.StringLiteral, Value: "5"}, query.Literal{Kind: query.StringLiteral, Value: "6"}},
+				},
 			},
 			Err: nil,
 		},
-	}
-
-	output := output{Types: query.TypeString, Operators: query.OperatorString}
-	for _, tc := range ts {
-		t.Run(tc.Name, func(t *testing.T) {
-			actual, err := ParseMany([]string{tc.SQL})
-			if tc.Err != nil && err == nil {
-				t.Errorf("Error should have been %v", tc.Err)
-			}
-			if tc.Err == nil && err != nil {
-				t.Errorf("Error should have been nil but was %v", err)
-			}
-			if tc.Err != nil && err != nil {
-				require.Equal(t, tc.Err, err, "Unexpected error")
-			}
-			if len(actual) > 0 {
-				require.Equal(t, tc.Expected, actual[0], "Query didn't match expectation")
-			}
-			if tc.Err != nil {
-				output.ErrorExamples = append(output.ErrorExamples, tc)
-			} else {
-				output.NoErrorExamples = append(output.NoErrorExamples, tc)
-			}
-		})
-	}
-	createReadme(output)
+		{
+			Name: "INSERT without fields works",
+			SQL:  "INSERT INTO 'a' VALUES ('1','2'),('3','4')",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Inserts: [][]query.Expr{
+					{query.Literal{Kind: query.StringLiteral, Value: "1"}, query.Literal{Kind: query.StringLiteral, Value: "2"}},
+					{query.Literal{Kind: query.StringLiteral, Value: "3"}, query.Literal{Kind: query.StringLiteral, Value: "4"}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "INSERT without fields with uneven rows fails",
+			SQL:      "INSERT INTO 'a' VALUES ('1','2'),('3')",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at INSERT INTO: value count doesn't match field count"),
+		},
+		{
+			Name: "INSERT with string literal variants works",
+			SQL:  "INSERT INTO 'a' (b, c, d, e) VALUES (E'it\\'s\\n', N'café', $$it's$$, $x$a$$b$x$)",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Fields:    []string{"b", "c", "d", "e"},
+				Inserts: [][]query.Expr{{
+					query.Literal{Kind: query.StringLiteral, Value: "it's\n", Raw: "E'it\\'s\\n'"},
+					query.Literal{Kind: query.StringLiteral, Value: "café", Raw: "N'café'"},
+					query.Literal{Kind: query.StringLiteral, Value: "it's", Raw: "$$it's$$"},
+					query.Literal{Kind: query.StringLiteral, Value: "a$$b", Raw: "$x$a$$b$x$"},
+				}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "INSERT with numbers works",
+			SQL:  "INSERT INTO 'a' (b, c, d, e, f, g, h, i) VALUES (42, -7, 19.990, .5, 1e10, -2.5E-3, 0xFF, 1-2)",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Fields:    []string{"b", "c", "d", "e", "f", "g", "h", "i"},
+				Inserts: [][]query.Expr{{
+					query.Literal{Kind: query.IntegerLiteral, Value: "42"},
+					query.Literal{Kind: query.IntegerLiteral, Value: "-7"},
+					query.Literal{Kind: query.DecimalLiteral, Value: "19.990"},
+					query.Literal{Kind: query.DecimalLiteral, Value: ".5"},
+					query.Literal{Kind: query.FloatLiteral, Value: "1e10"},
+					query.Literal{Kind: query.FloatLiteral, Value: "-2.5E-3"},
+					query.Literal{Kind: query.IntegerLiteral, Value: "0xFF"},
+					query.BinaryExpr{Left: query.Literal{Kind: query.IntegerLiteral, Value: "1"}, Operator: "-", Right: query.Literal{Kind: query.IntegerLiteral, Value: "2"}},
+				}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "INSERT with RETURNING works",
+			SQL:  "INSERT INTO 'a' (b) VALUES ('1') RETURNING id, created_at AS c",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Fields:    []string{"b"},
+				Inserts:   [][]query.Expr{{query.Literal{Kind: query.StringLiteral, Value: "1"}}},
+				Returning: []query.Expr{
+					query.Column{Name: "id"},
+					query.AliasedExpr{Expr: query.Column{Name: "created_at"}, Alias: "c"},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "INSERT with OUTPUT works",
+			SQL:  "INSERT INTO 'a' (b) OUTPUT inserted.id VALUES ('1')",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Fields:    []string{"b"},
+				Inserts:   [][]query.Expr{{query.Literal{Kind: query.StringLiteral, Value: "1"}}},
+				Returning: []query.Expr{query.Column{Table: "inserted", Name: "id"}},
+				Output:    true,
+			},
+			Err: nil,
+		},
+		{
+			Name: "INSERT with OUTPUT and no field list works",
+			SQL:  "INSERT INTO 'a' OUTPUT inserted.id VALUES ('1')",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Inserts:   [][]query.Expr{{query.Literal{Kind: query.StringLiteral, Value: "1"}}},
+				Returning: []query.Expr{query.Column{Table: "inserted", Name: "id"}},
+				Output:    true,
+			},
+			Err: nil,
+		},
+		{
+			Name:     "INSERT with empty RETURNING fails",
+			SQL:      "INSERT INTO 'a' (b) VALUES ('1') RETURNING",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at RETURNING: expected field to return"),
+		},
+		{
+			Name: "INSERT with ON DUPLICATE KEY UPDATE works",
+			SQL:  "INSERT INTO 'a' (b, c) VALUES (1, 2) ON DUPLICATE KEY UPDATE b = VALUES(b), c = c + 1",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Fields:    []string{"b", "c"},
+				Inserts: [][]query.Expr{{
+					query.Literal{Kind: query.IntegerLiteral, Value: "1"},
+					query.Literal{Kind: query.IntegerLiteral, Value: "2"},
+				}},
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{query.FuncCall{Name: "VALUES", Args: []query.Expr{query.Column{Name: "b"}}}}},
+					{Fields: []string{"c"}, Values: []query.Expr{query.BinaryExpr{Left: query.Column{Name: "c"}, Operator: "+", Right: query.Literal{Kind: query.IntegerLiteral, Value: "1"}}}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "INSERT with empty ON DUPLICATE KEY UPDATE fails",
+			SQL:      "INSERT INTO 'a' (b) VALUES (1) ON DUPLICATE KEY UPDATE",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at ON DUPLICATE KEY UPDATE: expected field to update"),
+		},
+		{
+			Name:     "INSERT with ON DUPLICATE KEY UPDATE without value fails",
+			SQL:      "INSERT INTO 'a' (b) VALUES (1) ON DUPLICATE KEY UPDATE b =",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at ON DUPLICATE KEY UPDATE: expected value for b"),
+		},
+		{
+			Name: "UPDATE with RETURNING works",
+			SQL:  "UPDATE 'a' SET b = 'hello' WHERE a = '1' RETURNING *",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "hello"}}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+				Returning: []query.Expr{query.Column{Name: "*"}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "UPDATE with OUTPUT works",
+			SQL:  "UPDATE 'a' SET b = 'hello' OUTPUT deleted.b, inserted.b WHERE a = '1'",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "hello"}}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+				Returning: []query.Expr{
+					query.Column{Table: "deleted", Name: "b"},
+					query.Column{Table: "inserted", Name: "b"},
+				},
+				Output: true,
+			},
+			Err: nil,
+		},
+		{
+			Name: "UPDATE with OUTPUT before FROM works",
+			SQL:  "UPDATE t SET a = 1 OUTPUT inserted.a FROM t JOIN u ON t.id = u.id WHERE u.b = 2",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "t",
+				Updates: []query.Assignment{
+					{Fields: []string{"a"}, Values: []query.Expr{query.Literal{Kind: query.IntegerLiteral, Value: "1"}}},
+				},
+				From: []string{"t"},
+				Joins: []query.Join{
+					{Type: "JOIN", Table: "u", Conditions: []query.JoinCondition{
+						{Table1: "t", Operand1: "id", Operator: query.Eq, Table2: "u", Operand2: "id"},
+					}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "u.b", Operand1IsField: true, Operator: query.Eq, Operand2: "2", Operand2IsField: false, Operand2Kind: query.IntegerLiteral},
+				},
+				Returning: []query.Expr{query.Column{Table: "inserted", Name: "a"}},
+				Output:    true,
+			},
+			Err: nil,
+		},
+		{
+			Name:     "UPDATE with JOINs both before SET and after FROM fails",
+			SQL:      "UPDATE t JOIN u ON t.id = u.id SET a = 1 FROM v JOIN w ON v.id = w.id WHERE a = 2",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at UPDATE: expected JOINs either before SET or after FROM"),
+		},
+		{
+			Name: "DELETE with RETURNING works",
+			SQL:  "DELETE FROM 'a' WHERE b = '1' RETURNING id",
+			Expected: query.Query{
+				Type:      query.Delete,
+				TableName: "a",
+				Conditions: []query.Condition{
+					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+				Returning: []query.Expr{query.Column{Name: "id"}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "DELETE with OUTPUT works",
+			SQL:  "DELETE FROM 'a' OUTPUT deleted.* WHERE b = '1'",
+			Expected: query.Query{
+				Type:      query.Delete,
+				TableName: "a",
+				Conditions: []query.Condition{
+					{Operand1: "b", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+				Returning: []query.Expr{query.Column{Table: "deleted", Name: "*"}},
+				Output:    true,
+			},
+			Err: nil,
+		},
+		{
+			Name:     "DELETE with RETURNING before WHERE fails",
+			SQL:      "DELETE FROM 'a' RETURNING id WHERE b = '1'",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("expected WHERE"),
+		},
+		{
+			Name:     "BEGIN works",
+			SQL:      "BEGIN",
+			Expected: query.Query{Type: query.Begin},
+			Err:      nil,
+		},
+		{
+			Name:     "START TRANSACTION works",
+			SQL:      "START TRANSACTION",
+			Expected: query.Query{Type: query.Begin},
+			Err:      nil,
+		},
+		{
+			Name:     "COMMIT WORK works",
+			SQL:      "COMMIT WORK",
+			Expected: query.Query{Type: query.Commit},
+			Err:      nil,
+		},
+		{
+			Name:     "ROLLBACK TO SAVEPOINT works",
+			SQL:      "ROLLBACK TO SAVEPOINT before_import",
+			Expected: query.Query{Type: query.Rollback, Savepoint: "before_import"},
+			Err:      nil,
+		},
+		{
+			Name:     "ROLLBACK TO without savepoint fails",
+			SQL:      "ROLLBACK TO",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at SAVEPOINT: expected savepoint name"),
+		},
+		{
+			Name:     "SAVEPOINT works",
+			SQL:      "SAVEPOINT before_import",
+			Expected: query.Query{Type: query.Savepoint, Savepoint: "before_import"},
+			Err:      nil,
+		},
+		{
+			Name:     "RELEASE SAVEPOINT works",
+			SQL:      "RELEASE SAVEPOINT before_import",
+			Expected: query.Query{Type: query.Release, Savepoint: "before_import"},
+			Err:      nil,
+		},
+		{
+			Name: "SET with a list of values works",
+			SQL:  "SET search_path = app, public",
+			Expected: query.Query{
+				Type: query.Set,
+				Updates: []query.Assignment{
+					{Fields: []string{"search_path"}, Values: []query.Expr{query.Column{Name: "app"}, query.Column{Name: "public"}}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SET with scope and many variables works",
+			SQL:  "SET SESSION time_zone = '+00:00', sql_mode TO 'ANSI'",
+			Expected: query.Query{
+				Type:  query.Set,
+				Scope: "SESSION",
+				Updates: []query.Assignment{
+					{Fields: []string{"time_zone"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "+00:00"}}},
+					{Fields: []string{"sql_mode"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "ANSI"}}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SET NAMES works",
+			SQL:  "SET NAMES utf8mb4",
+			Expected: query.Query{
+				Type: query.Set,
+				Updates: []query.Assignment{
+					{Fields: []string{"NAMES"}, Values: []query.Expr{query.Column{Name: "utf8mb4"}}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SET of a user variable works",
+			SQL:  "SET @x = 1",
+			Expected: query.Query{
+				Type: query.Set,
+				Updates: []query.Assignment{
+					{Fields: []string{"@x"}, Values: []query.Expr{query.Literal{Kind: query.IntegerLiteral, Value: "1"}}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SET from other variables works",
+			SQL:  "SET @x = @y + @@sql_safe_updates",
+			Expected: query.Query{
+				Type: query.Set,
+				Updates: []query.Assignment{
+					{Fields: []string{"@x"}, Values: []query.Expr{query.BinaryExpr{Left: query.Column{Name: "@y"}, Operator: "+", Right: query.Column{Name: "@@sql_safe_updates"}}}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "SET without value fails",
+			SQL:      "SET search_path =",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at SET: expected value for search_path"),
+		},
+		{
+			Name:     "USE works",
+			SQL:      "USE reporting",
+			Expected: query.Query{Type: query.Use, Database: "reporting"},
+			Err:      nil,
+		},
+		{
+			Name:     "USE without database fails",
+			SQL:      "USE",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at USE: expected database name"),
+		},
+		{
+			Name: "EXPLAIN ANALYZE works",
+			SQL:  "EXPLAIN ANALYZE SELECT a FROM 'b' WHERE c = '1'",
+			Expected: query.Query{
+				Type:    query.Explain,
+				Analyze: true,
+				Explained: &query.Query{
+					Type:      query.Select,
+					TableName: "b",
+					Fields:    []string{"a"},
+					Conditions: []query.Condition{
+						{Operand1: "c", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+					},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "EXPLAIN with invalid query fails",
+			SQL:      "EXPLAIN SELECT a",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at EXPLAIN: table name cannot be empty"),
+		},
+		{
+			Name:     "EXPLAIN without query fails",
+			SQL:      "EXPLAIN",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at EXPLAIN: expected query to explain"),
+		},
+		{
+			Name:     "SHOW TABLES works",
+			SQL:      "SHOW TABLES FROM reporting",
+			Expected: query.Query{Type: query.Show, Show: "TABLES", Database: "reporting"},
+			Err:      nil,
+		},
+		{
+			Name:     "SHOW COLUMNS works",
+			SQL:      "show columns from reporting.b",
+			Expected: query.Query{Type: query.Show, Show: "COLUMNS", Database: "reporting", TableName: "b"},
+			Err:      nil,
+		},
+		{
+			Name:     "SHOW COLUMNS without table fails",
+			SQL:      "SHOW COLUMNS",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at SHOW: expected FROM"),
+		},
+		{
+			Name:     "SHOW setting works",
+			SQL:      "SHOW search_path",
+			Expected: query.Query{Type: query.Show, Show: "search_path"},
+			Err:      nil,
+		},
+		{
+			Name:     "DESCRIBE works",
+			SQL:      "DESCRIBE b",
+			Expected: query.Query{Type: query.Describe, TableName: "b"},
+			Err:      nil,
+		},
+		{
+			Name:     "DESC works",
+			SQL:      "DESC reporting.b",
+			Expected: query.Query{Type: query.Describe, Database: "reporting", TableName: "b"},
+			Err:      nil,
+		},
+		{
+			Name: "GRANT works",
+			SQL:  "GRANT SELECT, INSERT, UPDATE (a, b) ON db.t TO reporting, 'app'@'%' WITH GRANT OPTION",
+			Expected: query.Query{
+				Type: query.Grant,
+				Privileges: []query.Privilege{
+					{Name: "SELECT"},
+					{Name: "INSERT"},
+					{Name: "UPDATE", Fields: []string{"a", "b"}},
+				},
+				Objects:     []query.Object{{Database: "db", Name: "t"}},
+				Grantees:    []string{"reporting", "app@%"},
+				GrantOption: true,
+			},
+			Err: nil,
+		},
+		{
+			Name: "GRANT on every table works",
+			SQL:  "GRANT ALL PRIVILEGES ON *.* TO admin",
+			Expected: query.Query{
+				Type:       query.Grant,
+				Privileges: []query.Privilege{{Name: "ALL PRIVILEGES"}},
+				Objects:    []query.Object{{Database: "*", Name: "*"}},
+				Grantees:   []string{"admin"},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "GRANT without grantee fails",
+			SQL:      "GRANT SELECT ON t",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at GRANT: expected TO"),
+		},
+		{
+			Name: "REVOKE works",
+			SQL:  "REVOKE GRANT OPTION FOR ALL ON TABLE a, b FROM reporting",
+			Expected: query.Query{
+				Type:        query.Revoke,
+				Privileges:  []query.Privilege{{Name: "ALL"}},
+				Objects:     []query.Object{{Kind: "TABLE", Name: "a"}, {Name: "b"}},
+				Grantees:    []string{"reporting"},
+				GrantOption: true,
+			},
+			Err: nil,
+		},
+		{
+			Name: "REVOKE on schema tables works",
+			SQL:  "REVOKE SELECT ON ALL TABLES IN SCHEMA public FROM PUBLIC",
+			Expected: query.Query{
+				Type:       query.Revoke,
+				Privileges: []query.Privilege{{Name: "SELECT"}},
+				Objects:    []query.Object{{Kind: "ALL TABLES IN SCHEMA", Name: "public"}},
+				Grantees:   []string{"PUBLIC"},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "REVOKE with TO fails",
+			SQL:      "REVOKE SELECT ON t TO reporting",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("at REVOKE: expected FROM"),
+		},
+		{
+			Name: "SELECT with placeholders works",
+			SQL:  "SELECT a FROM 'b' WHERE id = ? AND org = $2 AND tenant = :tenant AND d = '?'",
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "b",
+				Fields:    []string{"a"},
+				Conditions: []query.Condition{
+					{Operand1: "id", Operand1IsField: true, Operator: query.Eq, Operand2: "?", Operand2IsField: false, Operand2Kind: query.PlaceholderLiteral},
+					{Operand1: "org", Operand1IsField: true, Operator: query.Eq, Operand2: "$2", Operand2IsField: false, Operand2Kind: query.PlaceholderLiteral},
+					{Operand1: "tenant", Operand1IsField: true, Operator: query.Eq, Operand2: ":tenant", Operand2IsField: false, Operand2Kind: query.PlaceholderLiteral},
+					{Operand1: "d", Operand1IsField: true, Operator: query.Eq, Operand2: "?", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "INSERT with placeholders works",
+			SQL:  "INSERT INTO 'a' (b, c) VALUES (?, ?), (?, NULL)",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Fields:    []string{"b", "c"},
+				Inserts: [][]query.Expr{
+					{query.Literal{Kind: query.PlaceholderLiteral, Value: "?"}, query.Literal{Kind: query.PlaceholderLiteral, Value: "?"}},
+					{query.Literal{Kind: query.PlaceholderLiteral, Value: "?"}, query.Literal{Kind: query.NullLiteral, Value: "NULL"}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "UPDATE with placeholders works",
+			SQL:  "UPDATE 'a' SET b = $1, c = c + $2 WHERE d = $3",
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{query.Literal{Kind: query.PlaceholderLiteral, Value: "$1"}}},
+					{Fields: []string{"c"}, Values: []query.Expr{
+						query.BinaryExpr{Left: query.Column{Name: "c"}, Operator: "+", Right: query.Literal{Kind: query.PlaceholderLiteral, Value: "$2"}},
+					}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "d", Operand1IsField: true, Operator: query.Eq, Operand2: "$3", Operand2IsField: false, Operand2Kind: query.PlaceholderLiteral},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SELECT with comments works",
+			SQL:  "-- latest rows\nSELECT a, # first\n c /* second */ FROM 'b'\nORDER\tBY a -- done",
+			Expected: query.Query{
+				Type:        query.Select,
+				TableName:   "b",
+				Fields:      []string{"a", "c"},
+				OrderFields: []string{"a"},
+				OrderDir:    []string{"ASC"},
+				Comments: []query.Comment{
+					{Text: "-- latest rows", Start: 0, End: 14},
+					{Text: "# first", Start: 25, End: 32},
+					{Text: "/* second */", Start: 36, End: 48},
+					{Text: "-- done", Start: 69, End: 76},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "SELECT with optimizer hint works",
+			SQL:  "SELECT /*+ NO_ICP(b) */ a FROM 'b'",
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "b",
+				Fields:    []string{"a"},
+				Hints:     []query.Comment{{Text: "/*+ NO_ICP(b) */", Start: 7, End: 23}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "EXPLAIN attaches hints to the explained query",
+			SQL:  "/* console */ EXPLAIN SELECT /*+ MAX_EXECUTION_TIME(1000) */ a FROM 'b'",
+			Expected: query.Query{
+				Type:     query.Explain,
+				Comments: []query.Comment{{Text: "/* console */", Start: 0, End: 13}},
+				Explained: &query.Query{
+					Type:      query.Select,
+					TableName: "b",
+					Fields:    []string{"a"},
+					Hints:     []query.Comment{{Text: "/*+ MAX_EXECUTION_TIME(1000) */", Start: 29, End: 60}},
+				},
+			},
+			Err: nil,
+		},
+		{
+			Name: "Comment between the words of a keyword works",
+			SQL:  "SELECT a FROM t ORDER /* x */ BY a",
+			Expected: query.Query{
+				Type:        query.Select,
+				TableName:   "t",
+				Fields:      []string{"a"},
+				OrderFields: []string{"a"},
+				OrderDir:    []string{"ASC"},
+				Comments:    []query.Comment{{Text: "/* x */", Start: 22, End: 29}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "INSERT with optimizer hint works",
+			SQL:  "INSERT /*+ SET_VAR(a=1) */ INTO t (a) VALUES (1)",
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "t",
+				Fields:    []string{"a"},
+				Inserts:   [][]query.Expr{{query.Literal{Kind: query.IntegerLiteral, Value: "1"}}},
+				Hints:     []query.Comment{{Text: "/*+ SET_VAR(a=1) */", Start: 7, End: 26}},
+			},
+			Err: nil,
+		},
+		{
+			Name: "DELETE with optimizer hint works",
+			SQL:  "DELETE /*+ BNL(t) */ FROM t WHERE a = 1",
+			Expected: query.Query{
+				Type:      query.Delete,
+				TableName: "t",
+				Conditions: []query.Condition{
+					{Operand1: "a", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2IsField: false, Operand2Kind: query.IntegerLiteral},
+				},
+				Hints: []query.Comment{{Text: "/*+ BNL(t) */", Start: 7, End: 20}},
+			},
+			Err: nil,
+		},
+		{
+			Name:     "Unterminated comment fails",
+			SQL:      "SELECT a FROM 'b' /* oops",
+			Expected: query.Query{},
+			Err:      fmt.Errorf("unterminated comment"),
+		},
+	}
+
+	output := output{Types: query.TypeString, Operators: query.OperatorString}
+	for _, tc := range ts {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := ParseMany([]string{tc.SQL})
+			if tc.Err != nil && err == nil {
+				t.Errorf("Error should have been %v", tc.Err)
+			}
+			if tc.Err == nil && err != nil {
+				t.Errorf("Error should have been nil but was %v", err)
+			}
+			if tc.Err != nil && err != nil {
+				require.Equal(t, tc.Err, err, "Unexpected error")
+			}
+			if len(actual) > 0 {
+				require.Equal(t, tc.Expected, query.WithoutPositions(actual[0]), "Query didn't match expectation")
+			}
+			if tc.Err == nil && len(actual) > 0 {
+				requireRoundTrip(t, actual[0])
+				require.Equal(t, actual[0], query.Rewrite(actual[0], func(n query.Node) query.Node { return n }))
+				requireJSONRoundTrip(t, actual[0])
+			}
+			if tc.Err != nil {
+				output.ErrorExamples = append(output.ErrorExamples, tc)
+			} else {
+				output.NoErrorExamples = append(output.NoErrorExamples, tc)
+			}
+		})
+	}
+	createReadme(output)
+}
+
+// requireRoundTrip checks that the SQL of q parses back into q, but for the positions of its nodes and comments.
+func requireRoundTrip(t *testing.T, q query.Query) {
+	sql := q.SQL()
+	parsed, err := Parse(sql)
+	require.NoError(t, err, sql)
+	require.Equal(t, withoutCommentPositions(query.WithoutPositions(q)), withoutCommentPositions(query.WithoutPositions(parsed)), sql)
+}
+
+// requireJSONRoundTrip checks that the JSON encoding of q decodes back into q.
+func requireJSONRoundTrip(t *testing.T, q query.Query) {
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	var decoded query.Query
+	require.NoError(t, json.Unmarshal(data, &decoded), string(data))
+	require.Equal(t, q, decoded, string(data))
+}
+
+func withoutCommentPositions(q query.Query) query.Query {
+	clear := func(cs []query.Comment) []query.Comment {
+		if cs == nil {
+			return nil
+		}
+		cleared := make([]query.Comment, len(cs))
+		for i, c := range cs {
+			cleared[i] = query.Comment{Text: c.Text}
+		}
+		return cleared
+	}
+	q.Comments, q.Hints = clear(q.Comments), clear(q.Hints)
+	if q.Explained != nil {
+		explained := withoutCommentPositions(*q.Explained)
+		q.Explained = &explained
+	}
+	return q
+}
+
+func TestParams(t *testing.T) {
+	ts := []struct {
+		Name     string
+		SQL      string
+		Expected []query.Param
+	}{
+		{
+			Name:     "no placeholders",
+			SQL:      "SELECT a FROM 'b' WHERE c = '?'",
+			Expected: []query.Param{},
+		},
+		{
+			Name: "question marks are numbered in order",
+			SQL:  "UPDATE 'a' SET b = ?, c = COALESCE(?, c) WHERE d = ?",
+			Expected: []query.Param{
+				{Placeholder: "?", Index: 1},
+				{Placeholder: "?", Index: 2},
+				{Placeholder: "?", Index: 3},
+			},
+		},
+		{
+			Name: "numbered and named placeholders",
+			SQL:  "INSERT INTO 'a' (b, c, d) VALUES ($2, :tenant, :org)",
+			Expected: []query.Param{
+				{Placeholder: "$2", Index: 2},
+				{Placeholder: ":tenant", Name: "tenant"},
+				{Placeholder: ":org", Name: "org"},
+			},
+		},
+		{
+			Name:     "MySQL variables aren't placeholders",
+			SQL:      "SET @x = @y + @@sql_safe_updates",
+			Expected: []query.Param{},
+		},
+		{
+			Name:     "explained query",
+			SQL:      "EXPLAIN SELECT a FROM 'b' WHERE c = $1",
+			Expected: []query.Param{{Placeholder: "$1", Index: 1}},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.Name, func(t *testing.T) {
+			q, err := Parse(tc.SQL)
+			require.NoError(t, err)
+			require.Equal(t, tc.Expected, q.Params())
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	ts := []struct {
+		Name     string
+		SQL      string
+		Args     []interface{}
+		Named    map[string]interface{}
+		Expected query.Query
+		Err      error
+	}{
+		{
+			Name: "positional arguments replace placeholders with typed literals",
+			SQL:  "UPDATE 'a' SET b = ?, c = c + ?, d = ? WHERE e = ?",
+			Args: []interface{}{"O'Brien", 2.5, nil, 7},
+			Expected: query.Query{
+				Type:      query.Update,
+				TableName: "a",
+				Updates: []query.Assignment{
+					{Fields: []string{"b"}, Values: []query.Expr{query.Literal{Kind: query.StringLiteral, Value: "O'Brien"}}},
+					{Fields: []string{"c"}, Values: []query.Expr{
+						query.BinaryExpr{Left: query.Column{Name: "c"}, Operator: "+", Right: query.Literal{Kind: query.DecimalLiteral, Value: "2.5"}},
+					}},
+					{Fields: []string{"d"}, Values: []query.Expr{query.Literal{Kind: query.NullLiteral, Value: "NULL"}}},
+				},
+				Conditions: []query.Condition{
+					{Operand1: "e", Operand1IsField: true, Operator: query.Eq, Operand2: "7", Operand2IsField: false, Operand2Kind: query.IntegerLiteral},
+				},
+			},
+		},
+		{
+			Name: "numbered placeholders may repeat",
+			SQL:  "INSERT INTO 'a' (b, c) VALUES ($1, $1), ($2, TRUE)",
+			Args: []interface{}{true, []byte("x")},
+			Expected: query.Query{
+				Type:      query.Insert,
+				TableName: "a",
+				Fields:    []string{"b", "c"},
+				Inserts: [][]query.Expr{
+					{query.Literal{Kind: query.BoolLiteral, Value: "TRUE"}, query.Literal{Kind: query.BoolLiteral, Value: "TRUE"}},
+					{query.Literal{Kind: query.StringLiteral, Value: "x"}, query.Literal{Kind: query.BoolLiteral, Value: "TRUE"}},
+				},
+			},
+		},
+		{
+			Name:  "named arguments replace named placeholders",
+			SQL:   "SELECT a FROM 'b' WHERE c = :tenant AND d = :org",
+			Named: map[string]interface{}{"tenant": "acme", "org": int64(3)},
+			Expected: query.Query{
+				Type:      query.Select,
+				TableName: "b",
+				Fields:    []string{"a"},
+				Conditions: []query.Condition{
+					{Operand1: "c", Operand1IsField: true, Operator: query.Eq, Operand2: "acme", Operand2IsField: false, Operand2Kind: query.StringLiteral},
+					{Operand1: "d", Operand1IsField: true, Operator: query.Eq, Operand2: "3", Operand2IsField: false, Operand2Kind: query.IntegerLiteral},
+				},
+			},
+		},
+		{
+			Name: "too few arguments fails",
+			SQL:  "SELECT a FROM 'b' WHERE c = ? AND d = ?",
+			Args: []interface{}{1},
+			Err:  fmt.Errorf("missing argument for parameter ?: got 1 arguments"),
+		},
+		{
+			Name: "too many arguments fails",
+			SQL:  "SELECT a FROM 'b' WHERE c = ?",
+			Args: []interface{}{1, 2},
+			Err:  fmt.Errorf("argument 2 doesn't match any parameter"),
+		},
+		{
+			Name: "unsupported argument type fails",
+			SQL:  "SELECT a FROM 'b' WHERE c = ?",
+			Args: []interface{}{struct{}{}},
+			Err:  fmt.Errorf("argument for parameter ? has unsupported type struct {}"),
+		},
+		{
+			Name: "named placeholder bound by position fails",
+			SQL:  "SELECT a FROM 'b' WHERE c = :c",
+			Args: []interface{}{1},
+			Err:  fmt.Errorf("named parameter :c can't be bound by position"),
+		},
+		{
+			Name:  "unused named argument fails",
+			SQL:   "SELECT a FROM 'b' WHERE c = :c",
+			Named: map[string]interface{}{"c": 1, "d": 2},
+			Err:   fmt.Errorf("argument d doesn't match any parameter"),
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.Name, func(t *testing.T) {
+			q, err := Parse(tc.SQL)
+			require.NoError(t, err)
+			original, _ := Parse(tc.SQL)
+			var actual query.Query
+			if tc.Named != nil {
+				actual, err = q.BindNamed(tc.Named)
+			} else {
+				actual, err = q.Bind(tc.Args...)
+			}
+			require.Equal(t, original, q, "Bind mustn't modify the original query")
+			if tc.Err != nil {
+				require.Equal(t, tc.Err, err, "Unexpected error")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Expected, query.WithoutPositions(actual), "Query didn't match expectation")
+		})
+	}
+}
+
+func TestBindEscapesBackslashes(t *testing.T) {
+	sql := "UPDATE t SET a = ? WHERE id = 1"
+	for _, dialect := range []Dialect{ANSI, MySQL} {
+		q, err := ParseOpts(sql, ParseOptions{Dialect: dialect})
+		require.NoError(t, err)
+		value := `\'; DROP TABLE users; -- C:\temp`
+		b, err := q.Bind(value)
+		require.NoError(t, err)
+		parsed, err := ParseOpts(b.SQL(), ParseOptions{Dialect: dialect})
+		require.NoError(t, err, b.SQL())
+		require.Equal(t, value, parsed.Updates[0].Values[0].(query.Literal).Value)
+	}
+
+	q, err := ParseOpts(sql, ParseOptions{Dialect: MySQL})
+	require.NoError(t, err)
+	b, err := q.Bind([]byte(`a\b`))
+	require.NoError(t, err)
+	require.Equal(t, `UPDATE t SET a = 'a\\b' WHERE id = 1`, b.SQL())
+	q, err = Parse(sql)
+	require.NoError(t, err)
+	b, err = q.Bind([]byte(`a\b`))
+	require.NoError(t, err)
+	require.Equal(t, `UPDATE t SET a = 'a\b' WHERE id = 1`, b.SQL())
+}
+
+func TestParseOpts(t *testing.T) {
+	sql := `INSERT INTO 'a' (b) VALUES ('it\'s \\ \%')`
+	_, err := Parse(sql)
+	require.Error(t, err)
+
+	q, err := ParseOpts(sql, ParseOptions{Dialect: MySQL})
+	require.NoError(t, err)
+	require.Equal(t, [][]query.Expr{{query.Literal{Kind: query.StringLiteral, Value: `it's \ \%`, Raw: `'it\'s \\ \%'`}}}, query.WithoutPositions(q).Inserts)
+}
+
+func TestParseOptsRecover(t *testing.T) {
+	ts := []struct {
+		Name     string
+		SQL      string
+		Expected string
+		Errs     []string
+	}{
+		{
+			Name:     "SELECT resumes at commas and clauses",
+			SQL:      "SELECT a, , b FROM t\nWHERE x = 1 y = 2\nORDER BY , c",
+			Expected: "SELECT a, b FROM t WHERE x = 1 ORDER BY c",
+			Errs: []string{
+				"at line 1, column 11: at SELECT: expected field to SELECT",
+				"at line 2, column 13: expected AND",
+				"at line 3, column 10: at ORDER BY: expected field to ORDER",
+			},
+		},
+		{
+			Name:     "SELECT skips GROUP BY",
+			SQL:      "SELECT a b FROM t WHERE x = 1 y GROUP BY a, b ORDER BY a",
+			Expected: "SELECT a FROM t WHERE x = 1 ORDER BY a",
+			Errs: []string{
+				"at line 1, column 10: at SELECT: expected comma or FROM",
+				"at line 1, column 31: expected AND",
+			},
+		},
+		{
+			Name:     "INSERT resumes at commas and closing parens",
+			SQL:      "INSERT INTO t (a, 1, b) VALUES (1, , 3), (4, 5)",
+			Expected: "INSERT INTO t (a, b) VALUES (1, 3), (4, 5)",
+			Errs: []string{
+				"at line 1, column 19: at INSERT INTO: expected at least one field to insert",
+				"at line 1, column 36: at INSERT INTO: expected quoted value",
+			},
+		},
+		{
+			Name:     "UPDATE resumes at commas and collects validation errors",
+			SQL:      "UPDATE t SET a = , b = 2 WHERE",
+			Expected: "UPDATE t SET a = , b = 2",
+			Errs: []string{
+				"at line 1, column 18: at UPDATE: expected quoted value",
+				"at line 1, column 31: at WHERE: empty WHERE clause",
+			},
+		},
+		{
+			Name:     "EXPLAIN recovers in the explained query",
+			SQL:      "EXPLAIN DELETE FROM t WHERE a = 1 ORDER BY , b",
+			Expected: "EXPLAIN DELETE FROM t WHERE a = 1 ORDER BY b",
+			Errs:     []string{"at line 1, column 44: at EXPLAIN: at ORDER BY: expected field to ORDER"},
+		},
+		{
+			Name:     "Unknown query type can't be recovered from",
+			SQL:      "BOGUS a FROM t",
+			Expected: "",
+			Errs:     []string{"at line 1, column 1: invalid query type"},
+		},
+		{
+			Name:     "Stray semicolon is skipped",
+			SQL:      "SELECT a FROM t ;",
+			Expected: "SELECT a FROM t",
+			Errs:     []string{"at line 1, column 17: at SELECT: expected quoted table name"},
+		},
+		{
+			Name:     "Stray characters are skipped",
+			SQL:      "SELECT a b ; FROM t",
+			Expected: "SELECT a FROM t",
+			Errs:     []string{"at line 1, column 10: at SELECT: expected comma or FROM"},
+		},
+		{
+			Name:     "Unknown character in a list is skipped",
+			SQL:      "SELECT a, ! FROM t",
+			Expected: "SELECT a FROM t",
+			Errs:     []string{"at line 1, column 11: at SELECT: expected field to SELECT"},
+		},
+		{
+			Name:     "Unknown character in a value is skipped",
+			SQL:      "UPDATE t SET a = ~ WHERE id = 1",
+			Expected: "UPDATE t SET a =  WHERE id = 1",
+			Errs:     []string{"at line 1, column 18: at UPDATE: expected quoted value"},
+		},
+		{
+			Name:     "Valid query has no errors",
+			SQL:      "SELECT a FROM t WHERE x = 1",
+			Expected: "SELECT a FROM t WHERE x = 1",
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.Name, func(t *testing.T) {
+			q, err := ParseOpts(tc.SQL, ParseOptions{Recover: true})
+			require.Equal(t, tc.Expected, q.SQL())
+			if tc.Errs == nil {
+				require.NoError(t, err)
+				return
+			}
+			errs, ok := err.(ParseErrors)
+			require.True(t, ok, "Error should have been ParseErrors but was %v", err)
+			messages := []string{}
+			for _, e := range errs {
+				messages = append(messages, e.Error())
+			}
+			require.Equal(t, tc.Errs, messages)
+			require.Equal(t, strings.Join(tc.Errs, "; "), err.Error())
+
+			// without recovery, parsing stops at the first error
+			q, err = ParseOpts(tc.SQL, ParseOptions{})
+			require.Equal(t, query.Query{}, q)
+			require.Equal(t, errs[0].Err, err)
+		})
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	script := `-- users
+INSERT INTO 'a' (b) VALUES ('x;y');
+UPDATE 'a' SET b = 'z' WHERE b = 'semi; colon' ;;
+/* done; really */
+DELIMITER //
+SELECT a FROM 'b' WHERE c = '//' //
+DELIMITER ;
+SELECT a FROM 'b' WHERE c = '1'`
+
+	stmts, err := ParseScript(script)
+	require.NoError(t, err)
+	require.Len(t, stmts, 4)
+	for _, stmt := range stmts {
+		require.Equal(t, stmt.SQL, script[stmt.Start:stmt.End])
+	}
+	require.Equal(t, "-- users\nINSERT INTO 'a' (b) VALUES ('x;y')", stmts[0].SQL)
+	require.Equal(t, query.Insert, stmts[0].Query.Type)
+	require.Equal(t, []query.Comment{{Text: "-- users", Start: 0, End: 8}}, stmts[0].Query.Comments)
+	require.Equal(t, "UPDATE 'a' SET b = 'z' WHERE b = 'semi; colon'", stmts[1].SQL)
+	require.Equal(t, "SELECT a FROM 'b' WHERE c = '//'", stmts[2].SQL)
+	require.Equal(t, "SELECT a FROM 'b' WHERE c = '1'", stmts[3].SQL)
+	require.Equal(t, query.Select, stmts[3].Query.Type)
+
+	quoted := `SELECT "a;b" FROM t; SELECT [c;d] FROM t; SELECT x FROM t WHERE y = E'it\'s;' AND z = 'it''s;';` +
+		"CREATE FUNCTION f() AS $body$ BEGIN; END; $body$; SELECT `e;f` FROM t"
+	stmts, _ = ParseScriptOpts(quoted, ScriptOptions{ContinueOnError: true})
+	sqls := []string{}
+	for _, stmt := range stmts {
+		sqls = append(sqls, stmt.SQL)
+	}
+	require.Equal(t, []string{
+		`SELECT "a;b" FROM t`,
+		`SELECT [c;d] FROM t`,
+		`SELECT x FROM t WHERE y = E'it\'s;' AND z = 'it''s;'`,
+		`CREATE FUNCTION f() AS $body$ BEGIN; END; $body$`,
+		"SELECT `e;f` FROM t",
+	}, sqls)
+
+	backslash := `SELECT a FROM t WHERE p = 'C:\'; SELECT b FROM t WHERE q = 'x'`
+	stmts, err = ParseScript(backslash)
+	require.NoError(t, err)
+	require.Len(t, stmts, 2)
+	require.Equal(t, `SELECT a FROM t WHERE p = 'C:\'`, stmts[0].SQL)
+	stmts, _ = ParseScriptOpts(`SELECT a FROM t WHERE p = 'it\'s;'; SELECT b FROM t`, ScriptOptions{Dialect: MySQL})
+	require.Len(t, stmts, 2)
+	require.Equal(t, `SELECT a FROM t WHERE p = 'it\'s;'`, stmts[0].SQL)
+
+	broken := "SELECT a FROM 'b';\nBOGUS;\nDELETE FROM 'a' WHERE b = '1';"
+	stmts, err = ParseScript(broken)
+	require.Equal(t, fmt.Errorf("at line 2: invalid query type"), err)
+	require.Len(t, stmts, 1)
+
+	stmts, err = ParseScriptOpts(broken, ScriptOptions{ContinueOnError: true})
+	require.Equal(t, fmt.Errorf("at line 2: invalid query type"), err)
+	require.Len(t, stmts, 3)
+	require.NoError(t, stmts[0].Err)
+	require.Equal(t, fmt.Errorf("invalid query type"), stmts[1].Err)
+	require.NoError(t, stmts[2].Err)
+	require.Equal(t, query.Delete, stmts[2].Query.Type)
+}
+
+func TestStatementReader(t *testing.T) {
+	dump := "-- dump\nINSERT INTO `a` VALUES ('x;(y'),('2');\n/*!40000 ALTER TABLE `a` ENABLE KEYS */;\n" +
+		"BOGUS;\nSELECT b FROM 'a' WHERE c = '1'"
+	r := NewStatementReader(iotest.OneByteReader(strings.NewReader(dump)))
+	q, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, query.Insert, q.Type)
+	require.Equal(t, [][]query.Expr{
+		{query.Literal{Kind: query.StringLiteral, Value: "x;(y"}},
+		{query.Literal{Kind: query.StringLiteral, Value: "2"}},
+	}, query.WithoutPositions(q).Inserts)
+	_, err = r.Next()
+	require.Equal(t, fmt.Errorf("at line 4: invalid query type"), err)
+	q, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, query.Select, q.Type)
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+
+	rows := []string{}
+	for i := 0; i < 100; i++ {
+		rows = append(rows, fmt.Sprintf("('%v', '(%v)')", i, i))
+	}
+	big := "INSERT INTO 'a' (b, c) VALUES\n" + strings.Join(rows, ",\n") + ";\nSELECT b FROM 'a'"
+	r = NewStatementReader(iotest.OneByteReader(strings.NewReader(big)))
+	r.MaxInsertSize = 200
+	inserted := 0
+	batches := 0
+	for {
+		q, err := r.Next()
+		require.NoError(t, err)
+		if q.Type == query.Select {
+			break
+		}
+		require.Equal(t, []string{"b", "c"}, q.Fields)
+		for _, row := range query.WithoutPositions(q).Inserts {
+			require.Equal(t, query.Literal{Kind: query.StringLiteral, Value: fmt.Sprint(inserted)}, row[0])
+			inserted++
+		}
+		batches++
+	}
+	require.Equal(t, 100, inserted)
+	require.True(t, batches > 1)
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+
+	// batches are cut at MaxInsertSize even when far more than that is read at once, and after leading comments
+	rows = []string{}
+	for i := 0; i < 5000; i++ {
+		rows = append(rows, fmt.Sprintf("(%v, 'x')", i))
+	}
+	big = "/*!40000 ALTER TABLE a DISABLE KEYS */;\n-- rows\nINSERT INTO a (b, c) VALUES " + strings.Join(rows, ",") + ";"
+	r = NewStatementReader(strings.NewReader(big))
+	r.MaxInsertSize = 1000
+	inserted = 0
+	batches = 0
+	for {
+		q, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.True(t, len(q.SQL()) < 1200, q.SQL())
+		require.Equal(t, []string{"b", "c"}, q.Fields)
+		for _, row := range q.Inserts {
+			require.Equal(t, fmt.Sprint(inserted), row[0].(query.Literal).Value)
+			inserted++
+		}
+		batches++
+	}
+	require.Equal(t, 5000, inserted)
+	require.True(t, batches > len(big)/1200)
+
+	r = NewStatementReader(iotest.OneByteReader(strings.NewReader(`INSERT INTO a VALUES ('it\'s;');SELECT b FROM a`)))
+	r.Dialect = MySQL
+	q, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, query.Insert, q.Type)
+	q, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, query.Select, q.Type)
+}
+
+func TestParseManyOpts(t *testing.T) {
+	sqls := []string{}
+	for i := 0; i < 1000; i++ {
+		sqls = append(sqls, fmt.Sprintf("SELECT a FROM 'b' WHERE c = '%v'", i))
+	}
+	sqls[300] = "BOGUS"
+	sqls[700] = "SELECT"
+
+	qs, errs, err := ParseManyOpts(context.Background(), sqls, ManyOptions{Workers: 8, ContinueOnError: true})
+	require.Equal(t, fmt.Errorf("at index 300: invalid query type"), err)
+	require.Len(t, qs, 1000)
+	require.Len(t, errs, 1000)
+	for i := range sqls {
+		if i == 300 || i == 700 {
+			require.Error(t, errs[i])
+			require.Equal(t, query.Query{}, qs[i])
+			continue
+		}
+		require.NoError(t, errs[i])
+		require.Equal(t, fmt.Sprint(i), qs[i].Conditions[0].Operand2)
+	}
+
+	qs, errs, err = ParseManyOpts(context.Background(), sqls, ManyOptions{Workers: 8})
+	require.Equal(t, fmt.Errorf("at index 300: invalid query type"), err)
+	for i := 0; i < 300; i++ {
+		require.NoError(t, errs[i])
+		require.Equal(t, fmt.Sprint(i), qs[i].Conditions[0].Operand2)
+	}
+	require.Equal(t, query.Query{}, qs[300])
+	for i := 301; i < 1000; i++ {
+		require.Equal(t, ErrNotParsed, errs[i])
+		require.Equal(t, query.Query{}, qs[i])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errs, err = ParseManyOpts(ctx, sqls, ManyOptions{})
+	require.Equal(t, context.Canceled, err)
+	for _, err := range errs {
+		require.Equal(t, context.Canceled, err)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	q, err := Parse("UPDATE db.a SET n = n + 1 FROM b WHERE a.id = 'x' RETURNING a.id")
+	require.NoError(t, err)
+
+	var visited []string
+	query.Inspect(q, func(n query.Node) bool {
+		switch n := n.(type) {
+		case query.TableRef:
+			visited = append(visited, "table "+n.Database+"."+n.Name)
+		case query.Column:
+			visited = append(visited, "column "+q.ExprSQL(n))
+		case query.Literal:
+			visited = append(visited, "literal "+q.ExprSQL(n))
+		case query.BinaryExpr:
+			visited = append(visited, "binary "+q.ExprSQL(n))
+		}
+		return true
+	})
+	require.Equal(t, []string{
+		"table db.a",
+		"column n",
+		"binary n + 1",
+		"column n",
+		"literal 1",
+		"table .b",
+		"column a.id",
+		"literal 'x'",
+		"column a.id",
+	}, visited)
+
+	var tables int
+	query.Inspect(q, func(n query.Node) bool {
+		if _, ok := n.(query.TableRef); ok {
+			tables++
+		}
+		_, ok := n.(query.Assignment)
+		return !ok
+	})
+	require.Equal(t, 2, tables)
+
+	q, err = Parse("EXPLAIN SELECT a AS x FROM t JOIN u ON t.id = u.t_id")
+	require.NoError(t, err)
+	depth, maxDepth := 0, 0
+	query.Inspect(q, func(n query.Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+		depth++
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		return true
+	})
+	require.Equal(t, 0, depth)
+	require.Equal(t, 5, maxDepth) // Query, Query, Join, JoinCondition, Column
+}
+
+func TestRewrite(t *testing.T) {
+	q, err := Parse("SELECT a, b AS c FROM t JOIN u ON t.id = u.t_id WHERE x = '1' AND y = '2' ORDER BY a DESC, b")
+	require.NoError(t, err)
+	original := q.SQL()
+
+	tenant := func(n query.Node) query.Node {
+		switch n := n.(type) {
+		case query.TableRef:
+			if n.Database == "" {
+				n.Database = "tenant1"
+			}
+			return n
+		case query.Column:
+			if n.Name == "b" {
+				return nil
+			}
+		case query.Condition:
+			if n.Operand1 == "y" {
+				return nil
+			}
+		case query.Query:
+			n.Conditions = append(n.Conditions, query.Condition{
+				Operand1: "tenant_id", Operand1IsField: true, Operator: query.Eq, Operand2: "1", Operand2Kind: query.IntegerLiteral,
+			})
+			return n
+		}
+		return n
+	}
+	rewritten := query.Rewrite(q, tenant).(query.Query)
+	require.Equal(t, "SELECT a FROM tenant1.t JOIN tenant1.u ON t.id = u.t_id WHERE x = '1' AND tenant_id = 1 ORDER BY a DESC",
+		rewritten.SQL())
+	require.Equal(t, original, q.SQL())
+
+	q, err = Parse("UPDATE t SET n = n + 1, m = 2 WHERE id = 3 RETURNING n")
+	require.NoError(t, err)
+	original = q.SQL()
+	rewritten = query.Rewrite(q, func(n query.Node) query.Node {
+		if l, ok := n.(query.Literal); ok && l.Value == "1" {
+			return query.Literal{Kind: query.IntegerLiteral, Value: "10"}
+		}
+		if a, ok := n.(query.Assignment); ok && a.Fields[0] == "m" {
+			return nil
+		}
+		return n
+	}).(query.Query)
+	require.Equal(t, "UPDATE t SET n = n + 10 WHERE id = 3 RETURNING n", rewritten.SQL())
+	require.Equal(t, original, q.SQL())
+
+	q, err = Parse("UPDATE t SET (a, b, c) = (1, 2, 3), d = 4 WHERE id = 5")
+	require.NoError(t, err)
+	rewritten = query.Rewrite(q, func(n query.Node) query.Node {
+		if c, ok := n.(query.Column); ok && (c.Name == "a" || c.Name == "d") {
+			return nil
+		}
+		if l, ok := n.(query.Literal); ok && l.Value == "3" {
+			return nil
+		}
+		return n
+	}).(query.Query)
+	require.Equal(t, "UPDATE t SET b = 2 WHERE id = 5", rewritten.SQL())
+}
+
+func TestJSON(t *testing.T) {
+	const noPosition = `"Position":{"Start":0,"End":0,"Line":0,"Column":0}`
+	q, err := Parse("UPDATE t SET n = -n + 1 WHERE id = ? RETURNING n AS m")
+	require.NoError(t, err)
+	data, err := json.Marshal(query.WithoutPositions(q))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `{"Version":1,"Type":"Update",`)
+	require.Contains(t, string(data), `"Updates":[{"Fields":["n"],"Values":[{"Node":"BinaryExpr",`+
+		`"Left":{"Node":"UnaryExpr","Operator":"-","Operand":{"Node":"Column","Table":"","Name":"n",`+noPosition+`},`+noPosition+`},`+
+		`"Operator":"+","Right":{"Node":"Literal","Kind":"IntegerLiteral","Value":"1","Raw":"",`+noPosition+`},`+noPosition+`}],`+
+		noPosition+`,"FieldPositions":null}]`)
+	require.Contains(t, string(data), `"Conditions":[{"Operand1":"id","Operand1IsField":true,"Operator":"Eq",`+
+		`"Operand2":"?","Operand2IsField":false,"Operand2Kind":"PlaceholderLiteral","Operand2Raw":"","Operand2Expr":null,`+noPosition+`,`)
+	require.Contains(t, string(data), `"Returning":[{"Node":"AliasedExpr","Expr":{"Node":"Column","Table":"","Name":"n",`+
+		noPosition+`},"Alias":"m",`+noPosition+`}]`)
+
+	data, err = json.Marshal(q)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Returning":[{"Node":"AliasedExpr","Expr":{"Node":"Column","Table":"","Name":"n",`+
+		`"Position":{"Start":47,"End":48,"Line":1,"Column":48}},"Alias":"m","Position":{"Start":47,"End":53,"Line":1,"Column":48}}]`)
+	require.Contains(t, string(data), `"TablePosition":{"Start":7,"End":8,"Line":1,"Column":8}`)
+
+	e, err := query.UnmarshalExprJSON([]byte(`{"Node":"FuncCall","Name":"COALESCE","Args":[{"Node":"Column","Name":"a"},null]}`))
+	require.NoError(t, err)
+	require.Equal(t, query.FuncCall{Name: "COALESCE", Args: []query.Expr{query.Column{Name: "a"}, nil}}, e)
+
+	var decoded query.Query
+	require.Equal(t, fmt.Errorf("unsupported query JSON version 2"), json.Unmarshal([]byte(`{"Version":2}`), &decoded))
+	require.EqualError(t, json.Unmarshal([]byte(`{"Type":"Merge"}`), &decoded), `unknown query type "Merge"`)
+	require.EqualError(t, json.Unmarshal([]byte(`{"Returning":[{"Node":"Subquery"}]}`), &decoded),
+		`unknown expression node "Subquery"`)
+	_, err = json.Marshal(query.Query{Type: query.Type(100)})
+	require.Error(t, err)
+}
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(sql string) (string, uint64) {
+		q, err := Parse(sql)
+		require.NoError(t, err)
+		return query.Fingerprint(q)
+	}
+
+	sql, hash := fingerprint("SELECT a, b AS c FROM t JOIN u ON u.t_id = t.id WHERE y = 'x' AND x = 1")
+	require.Equal(t, "select a, b from t join u on t.id = u.t_id where x = ? and y = ?", sql)
+	for _, same := range []string{
+		"select  a,\n b AS d from t JOIN u ON u.t_id = t.id WHERE x = 2 AND y = 'it''s' -- note",
+		"/* note */ SELECT a, b FROM t JOIN u ON t.id = u.t_id WHERE y = ? AND x = $1",
+	} {
+		otherSQL, otherHash := fingerprint(same)
+		require.Equal(t, sql, otherSQL, same)
+		require.Equal(t, hash, otherHash, same)
+	}
+	for _, different := range []string{
+		"SELECT b, a FROM t JOIN u ON u.t_id = t.id WHERE y = 'x' AND x = 1",
+		"SELECT a, b FROM t JOIN u ON u.t_id = t.id WHERE y = 'x' AND z = 1",
+		"SELECT a, b FROM t JOIN u ON u.t_id = t.id WHERE y = 'x' AND x = z",
+	} {
+		_, otherHash := fingerprint(different)
+		require.NotEqual(t, hash, otherHash, different)
+	}
+
+	sql, _ = fingerprint("INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y'), (3, NULL)")
+	require.Equal(t, "insert into t (a, b) values (?, ?)", sql)
+	sql, _ = fingerprint("UPDATE \"Order\" SET n = n + 1 WHERE id = -5 RETURNING n AS m")
+	require.Equal(t, "update \"Order\" set n = n + ? where id = ? returning n", sql)
+	sql, _ = fingerprint("EXPLAIN DELETE FROM t WHERE a = 'x'")
+	require.Equal(t, "explain delete from t where a = ?", sql)
+
+	sql, hash = fingerprint("SELECT a FROM t ORDER BY a LIMIT 10")
+	require.Equal(t, "select a from t order by a limit ?", sql)
+	_, otherHash := fingerprint("SELECT a FROM t ORDER BY a LIMIT 20")
+	require.Equal(t, hash, otherHash)
+	sql, _ = fingerprint("SELECT TOP 5 a FROM t")
+	require.Equal(t, "select top ? a from t", sql)
+	sql, _ = fingerprint("DELETE FROM t WHERE a = 1 LIMIT 100")
+	require.Equal(t, "delete from t where a = ? limit ?", sql)
+}
+
+func TestEquivalent(t *testing.T) {
+	ts := []struct {
+		A, B       string
+		Opts       query.EquivalentOptions
+		Equivalent bool
+	}{
+		{A: "SELECT a FROM t WHERE b = 'it''s'", B: "/* note */ SELECT a FROM t WHERE b = E'it\\'s'", Equivalent: true},
+		{A: "SELECT a FROM t WHERE a = 1 AND b = 2", B: "SELECT a FROM t WHERE b = 2 AND a = 1", Equivalent: false},
+		{A: "SELECT a FROM t WHERE a = 1 AND b = 2", B: "SELECT a FROM t WHERE b = 2 AND a = 1",
+			Opts: query.EquivalentOptions{IgnoreConditionOrder: true}, Equivalent: true},
+		{A: "SELECT a FROM t JOIN u ON t.a = u.a AND t.b = u.b", B: "SELECT a FROM t JOIN u ON t.b = u.b AND t.a = u.a",
+			Opts: query.EquivalentOptions{IgnoreConditionOrder: true}, Equivalent: true},
+		{A: "SELECT a, b FROM t", B: "SELECT b, a FROM t",
+			Opts: query.EquivalentOptions{IgnoreConditionOrder: true, IgnoreAliases: true, IgnoreLiterals: true}, Equivalent: false},
+		{A: "SELECT a AS x FROM t", B: "SELECT a AS y FROM t", Equivalent: false},
+		{A: "SELECT a AS x FROM t", B: "SELECT a FROM t", Opts: query.EquivalentOptions{IgnoreAliases: true}, Equivalent: true},
+		{A: "UPDATE t SET a = 1 WHERE b = 'x' RETURNING a AS c", B: "UPDATE t SET a = 2 WHERE b = ? RETURNING a",
+			Opts: query.EquivalentOptions{IgnoreLiterals: true, IgnoreAliases: true}, Equivalent: true},
+		{A: "UPDATE t SET a = 1 WHERE b = 'x'", B: "UPDATE t SET a = 1 WHERE b = c",
+			Opts: query.EquivalentOptions{IgnoreLiterals: true}, Equivalent: false},
+		{A: "SELECT TOP 5 a FROM t", B: "SELECT a FROM t LIMIT 5", Equivalent: true},
+	}
+	for _, tc := range ts {
+		a, err := ParseOpts(tc.A, ParseOptions{})
+		require.NoError(t, err)
+		b, err := ParseOpts(tc.B, ParseOptions{})
+		require.NoError(t, err)
+		require.Equal(t, tc.Equivalent, query.Equivalent(a, b, tc.Opts), "%v / %v", tc.A, tc.B)
+		require.Equal(t, tc.Equivalent, query.Equivalent(b, a, tc.Opts), "%v / %v", tc.B, tc.A)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, err := Parse("SELECT a, b AS c FROM t JOIN u ON t.id = u.t_id WHERE x = 1 AND y = 2 ORDER BY a")
+	require.NoError(t, err)
+	require.Empty(t, query.Diff(a, a))
+
+	b, err := Parse("SELECT TOP 5 a, b AS d, e FROM db.t WHERE y = 2 AND x = '1' ORDER BY a DESC")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"table changed from t to db.t",
+		"field removed: b AS c",
+		"field added: b AS d",
+		"field added: e",
+		"join removed: JOIN u ON t.id = u.t_id",
+		"condition removed: x = 1",
+		"condition added: x = '1'",
+		"ORDER BY field removed: a",
+		"ORDER BY field added: a DESC",
+		"limit changed from 0 to 5",
+	}, query.Diff(a, b))
+
+	b, err = Parse("SELECT a, b AS c FROM t JOIN u ON t.id = u.t_id WHERE y = 2 AND x = 1 ORDER BY a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"conditions reordered from x = 1, y = 2 to y = 2, x = 1"}, query.Diff(a, b))
+
+	a, err = Parse("EXPLAIN DELETE FROM t WHERE a = 1")
+	require.NoError(t, err)
+	b, err = Parse("EXPLAIN UPDATE t SET b = 2 WHERE a = 1")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"explained query: type changed from Delete to Update",
+		"explained query: assignment added: b = 2",
+	}, query.Diff(a, b))
+
+	a, err = Parse("USE a")
+	require.NoError(t, err)
+	b, err = Parse("USE b")
+	require.NoError(t, err)
+	require.Equal(t, []string{"database changed from a to b"}, query.Diff(a, b))
+
+	a, err = Parse("SHOW TABLES")
+	require.NoError(t, err)
+	b, err = Parse("SHOW TABLES FROM x")
+	require.NoError(t, err)
+	require.Equal(t, []string{"database changed from none to x"}, query.Diff(a, b))
+}
+
+func TestPositions(t *testing.T) {
+	sql := "SELECT a, b AS c\nFROM db.t\nJOIN u ON t.id = u.t_id\nWHERE a = 'x' AND\n  b >= -5\nORDER BY a"
+	q, err := Parse(sql)
+	require.NoError(t, err)
+
+	var positioned []string
+	query.Inspect(q, func(n query.Node) bool {
+		var p query.Position
+		switch n := n.(type) {
+		case query.TableRef:
+			p = n.Position
+		case query.Join:
+			p = n.Position
+		case query.JoinCondition:
+			p = n.Position
+		case query.Condition:
+			p = n.Position
+		case query.Column:
+			p = n.Position
+		case query.Literal:
+			p = n.Position
+		default:
+			return true
+		}
+		positioned = append(positioned, fmt.Sprintf("%v:%v %v", p.Line, p.Column, sql[p.Start:p.End]))
+		return true
+	})
+	require.Equal(t, []string{
+		"2:6 db.t",
+		"1:8 a",
+		"1:11 b",
+		"3:1 JOIN u ON t.id = u.t_id",
+		"3:6 u",
+		"3:11 t.id = u.t_id",
+		"3:11 t.id",
+		"3:18 u.t_id",
+		"4:7 a = 'x'",
+		"4:7 a",
+		"4:11 'x'",
+		"5:3 b >= -5",
+		"5:3 b",
+		"5:8 -5",
+		"6:10 a",
+	}, positioned)
+
+	sql = "UPDATE t SET n = (n + 1) * 2,\n  m = COALESCE(m, 'x') WHERE id = 3 RETURNING n AS total"
+	q, err = Parse(sql)
+	require.NoError(t, err)
+	positioned = nil
+	query.Inspect(q, func(n query.Node) bool {
+		var p query.Position
+		switch n := n.(type) {
+		case query.Assignment:
+			p = n.Position
+		case query.BinaryExpr:
+			p = n.Position
+		case query.FuncCall:
+			p = n.Position
+		case query.AliasedExpr:
+			p = n.Position
+		default:
+			return true
+		}
+		positioned = append(positioned, fmt.Sprintf("%v:%v %v", p.Line, p.Column, sql[p.Start:p.End]))
+		return true
+	})
+	require.Equal(t, []string{
+		"1:14 n = (n + 1) * 2",
+		"1:18 (n + 1) * 2",
+		"1:18 (n + 1)",
+		"2:3 m = COALESCE(m, 'x')",
+		"2:7 COALESCE(m, 'x')",
+		"2:47 n AS total",
+	}, positioned)
+
+	// positions survive rewriting and binding, and can be dropped
+	bound, err := query.Rewrite(q, func(n query.Node) query.Node { return n }).(query.Query).Bind()
+	require.NoError(t, err)
+	require.Equal(t, q, bound)
+	require.Equal(t, query.Position{}, query.WithoutPositions(q).Updates[0].Position)
+	require.Nil(t, query.WithoutPositions(q).Updates[0].FieldPositions)
+}
+
+func TestPositionsLargeInsert(t *testing.T) {
+	// mysqldump writes each INSERT on a single line, so positions mustn't be found by scanning back along it
+	rows := make([]string, 20000)
+	for i := range rows {
+		rows[i] = fmt.Sprintf("(%d, 'name %d', %d.5)", i, i, i)
+	}
+	sql := "INSERT INTO t (a, b, c) VALUES " + strings.Join(rows, ",")
+	start := time.Now()
+	q, err := Parse(sql)
+	require.NoError(t, err)
+	require.True(t, time.Since(start) < 2*time.Second, "parsing %v bytes took %v", len(sql), time.Since(start))
+	last := q.Inserts[len(q.Inserts)-1][2].(query.Literal)
+	require.Equal(t, query.Position{Start: len(sql) - 8, End: len(sql) - 1, Line: 1, Column: len(sql) - 7}, last.Position)
 }
 
 func createReadme(out output) {